@@ -0,0 +1,132 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A BytesMode determines how a Formatter prints the contents of
+// byte slices and arrays.
+type BytesMode int
+
+const (
+	BytesList    BytesMode = iota // as integers, like other slices
+	BytesAuto                     // as BytesString if the bytes are text, otherwise as BytesHexDump
+	BytesString                   // as a quoted string
+	BytesHex                      // as hex integers, on a single line
+	BytesHexDump                  // as lines of a hex dump with offsets, like encoding/hex.Dump
+	BytesBase64                   // as a standard base64 encoding
+)
+
+// printBytes prints a byte slice or array according to mode.
+// In modes that can't be represented in Go syntax or on a single line,
+// it uses BytesHex instead if GoSyntax or Compact is set.
+func (s *state) printBytes(v reflect.Value, mode BytesMode) {
+	b := bytesOf(v)
+	if mode == BytesAuto {
+		if isText(b) {
+			mode = BytesString
+		} else {
+			mode = BytesHexDump
+		}
+	}
+	if mode == BytesHexDump && (s.compact || s.GoSyntax) {
+		mode = BytesHex
+	}
+	if s.GoSyntax && (mode == BytesBase64 || (mode == BytesString && v.Kind() == reflect.Array)) {
+		mode = BytesHex
+	}
+
+	switch mode {
+	case BytesString:
+		if s.GoSyntax {
+			s.prType(v.Type())
+			s.prc(punctClass, "(")
+			s.printString(string(b))
+			s.prc(punctClass, ")")
+		} else {
+			s.printString(string(b))
+		}
+
+	case BytesBase64:
+		s.prc(typeClass, "base64")
+		s.prc(punctClass, "(")
+		s.printString(base64.StdEncoding.EncodeToString(b))
+		s.prc(punctClass, ")")
+
+	case BytesHex:
+		s.printSliceType(v)
+		s.openBrace(v)
+		for i, c := range b {
+			if limit := s.maxElements(v.Type()); limit > 0 && i >= limit {
+				s.prc(markerClass, s.markers().Truncated)
+				break
+			}
+			s.prc(numberClass, fmt.Sprintf("0x%02x", c))
+			if i != len(b)-1 {
+				s.prc(punctClass, ", ")
+			}
+		}
+		s.prc(punctClass, "}")
+
+	case BytesHexDump:
+		s.printSliceType(v)
+		s.openBrace(v)
+		s.pr("\n")
+		truncated := false
+		if limit := s.maxElements(v.Type()); limit > 0 && len(b) > limit {
+			b = b[:limit]
+			truncated = true
+		}
+		s.depth++
+		for _, line := range strings.SplitAfter(hex.Dump(b), "\n") {
+			if line != "" {
+				s.pr(line)
+			}
+		}
+		if truncated {
+			s.prc(markerClass, s.markers().Truncated)
+			s.pr("\n")
+		}
+		s.depth--
+		s.prc(punctClass, "}")
+
+	default:
+		s.printSlice(v)
+	}
+}
+
+// bytesOf returns the contents of a byte slice or array.
+func bytesOf(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+	if v.CanAddr() {
+		return v.Bytes()
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b
+}
+
+// isText reports whether b is valid UTF-8 consisting of printable characters
+// and common whitespace.
+func isText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && r != '\n' && r != '\t' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package cmpformat connects the format package to
+// [github.com/google/go-cmp/cmp].
+// Its [Reporter] describes the differences found by cmp.Equal,
+// rendering the values at each mismatched path with a [format.Formatter],
+// so that the Formatter's settings, like IgnoreFields and MaxElements,
+// apply to the output.
+package cmpformat
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jba/format"
+)
+
+// Diff is like [cmp.Diff], but formats values with f.
+// It returns the empty string if x and y are equal.
+// If f is nil, the default Formatter is used.
+func Diff(x, y any, f *format.Formatter, opts ...cmp.Option) string {
+	r := &Reporter{Formatter: f}
+	cmp.Equal(x, y, append(slices.Clip(opts), cmp.Reporter(r))...)
+	return r.String()
+}
+
+// A Reporter is a [cmp.Reporter] that records a line for each difference,
+// like
+//
+//	Players[1].Score: got 11, want 12
+//
+// Pass it to cmp.Equal with [cmp.Reporter].
+type Reporter struct {
+	// Formatter formats the differing values.
+	// If nil, the default Formatter is used.
+	Formatter *format.Formatter
+
+	path  cmp.Path
+	lines []string
+}
+
+// PushStep implements cmp.Reporter.
+func (r *Reporter) PushStep(ps cmp.PathStep) {
+	r.path = append(r.path, ps)
+}
+
+// Report implements cmp.Reporter.
+func (r *Reporter) Report(rs cmp.Result) {
+	if rs.Equal() {
+		return
+	}
+	vx, vy := r.path.Last().Values()
+	var prefix string
+	if p := r.pathString(); p != "" {
+		prefix = p + ": "
+	}
+	r.lines = append(r.lines, fmt.Sprintf("%sgot %s, want %s\n",
+		prefix, r.format(vx), r.format(vy)))
+}
+
+// PopStep implements cmp.Reporter.
+func (r *Reporter) PopStep() {
+	r.path = r.path[:len(r.path)-1]
+}
+
+// String returns the differences reported so far, one per line.
+func (r *Reporter) String() string {
+	return strings.Join(r.lines, "")
+}
+
+// pathString returns the current path in the syntax of [format.Diff],
+// like Players[1].Score.
+func (r *Reporter) pathString() string {
+	var b strings.Builder
+	for _, ps := range r.path {
+		switch ps := ps.(type) {
+		case cmp.StructField:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(ps.Name())
+		case cmp.SliceIndex:
+			// Use the index in x, unless the element is only in y.
+			i, j := ps.SplitKeys()
+			if i < 0 {
+				i = j
+			}
+			fmt.Fprintf(&b, "[%d]", i)
+		case cmp.MapIndex:
+			fmt.Fprintf(&b, "[%s]", r.format(ps.Key()))
+		}
+	}
+	return b.String()
+}
+
+// format formats v on a single line.
+func (r *Reporter) format(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<missing>"
+	}
+	if !v.CanInterface() {
+		// An unexported field, compared with cmp.AllowUnexported or cmp.Exporter.
+		return fmt.Sprint(v)
+	}
+	f := r.Formatter
+	if f == nil {
+		f = format.New()
+	}
+	return fmt.Sprint(f.Val(v.Interface()))
+}
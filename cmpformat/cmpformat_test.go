@@ -0,0 +1,43 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package cmpformat
+
+import (
+	"testing"
+
+	"github.com/jba/format"
+)
+
+type player struct {
+	Name  string
+	Score int
+	Tags  []string
+}
+
+func TestDiff(t *testing.T) {
+	f := format.New(format.WithOmitPackage(true), format.WithMaxElements(2))
+	for _, test := range []struct {
+		x, y any
+		want string
+	}{
+		{1, 1, ""},
+		{1, 2, "got 1, want 2\n"},
+		{map[string]int{"a": 1}, map[string]int{"a": 2}, `["a"]: got 1, want 2` + "\n"},
+		{
+			[]player{{Name: "Al", Score: 1}, {Name: "Bo"}},
+			[]player{{Name: "Al", Score: 2}},
+			"[0].Score: got 1, want 2\n" +
+				`[1]: got player{Name: "Bo"}, want <missing>` + "\n",
+		},
+		{
+			player{Tags: []string{"a", "b", "c"}},
+			player{Tags: nil},
+			`Tags: got []{"a", "b", ...}, want []{}` + "\n",
+		},
+	} {
+		if got := Diff(test.x, test.y, f); got != test.want {
+			t.Errorf("Diff(%v, %v):\ngot\n%s\nwant\n%s", test.x, test.y, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"io"
+	"os"
+)
+
+// A Theme holds the ANSI escape sequences used to color each class of token
+// when [Formatter.Color] is set.
+// An empty sequence leaves that class uncolored.
+type Theme struct {
+	TypeName    string // type names, as in struct literals
+	FieldName   string // struct field names
+	String      string // string literals
+	Number      string // numeric literals
+	Keyword     string // nil, true and false
+	Punctuation string // braces, commas, colons and the like
+	Marker      string // placeholders like <cycle> and ...
+}
+
+// DefaultTheme is the Theme used when [Formatter.Theme] is nil.
+var DefaultTheme = Theme{
+	TypeName:    "\x1b[36m", // cyan
+	FieldName:   "\x1b[34m", // blue
+	String:      "\x1b[32m", // green
+	Number:      "\x1b[33m", // yellow
+	Keyword:     "\x1b[35m", // magenta
+	Punctuation: "",
+	Marker:      "\x1b[31m", // red
+}
+
+const colorReset = "\x1b[0m"
+
+// A class is a class of token, for coloring.
+type class int
+
+const (
+	plain class = iota
+	typeClass
+	fieldClass
+	stringClass
+	numberClass
+	keywordClass
+	punctClass
+	markerClass
+)
+
+func (t *Theme) escape(c class) string {
+	switch c {
+	case typeClass:
+		return t.TypeName
+	case fieldClass:
+		return t.FieldName
+	case stringClass:
+		return t.String
+	case numberClass:
+		return t.Number
+	case keywordClass:
+		return t.Keyword
+	case punctClass:
+		return t.Punctuation
+	case markerClass:
+		return t.Marker
+	default:
+		return ""
+	}
+}
+
+// theme returns the Theme to use when writing to w,
+// or nil if output should not be colored.
+// Following https://no-color.org, a non-empty NO_COLOR environment variable
+// disables color.
+func (f *Formatter) theme(w io.Writer) *Theme {
+	if !f.Color || os.Getenv("NO_COLOR") != "" || !isTerminal(w) {
+		return nil
+	}
+	if f.Theme != nil {
+		return f.Theme
+	}
+	return &DefaultTheme
+}
+
+// isTerminal reports whether w is a terminal.
+// It is a variable for testing.
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
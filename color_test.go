@@ -0,0 +1,50 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestColor(t *testing.T) {
+	defer func(f func(io.Writer) bool) { isTerminal = f }(isTerminal)
+	isTerminal = func(io.Writer) bool { return true }
+	t.Setenv("NO_COLOR", "")
+
+	f := &Formatter{
+		Compact:     true,
+		OmitPackage: true,
+		Color:       true,
+		Theme:       &Theme{TypeName: "<T>", FieldName: "<F>", String: "<S>", Number: "<N>"},
+	}
+	sprint := func(x any) string {
+		var sb strings.Builder
+		if err := f.Fprint(&sb, x); err != nil {
+			t.Fatal(err)
+		}
+		return sb.String()
+	}
+	got := sprint(Player{Name: "Al", Score: 11})
+	want := `<T>Player` + colorReset + `{<F>Name` + colorReset + `: <S>"Al"` + colorReset +
+		`, <F>Score` + colorReset + `: <N>11` + colorReset + `}`
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	got = sprint(Player{Name: "Al", Score: 11})
+	want = `Player{Name: "Al", Score: 11}`
+	if got != want {
+		t.Errorf("with NO_COLOR: got %q, want %q", got, want)
+	}
+
+	isTerminal = func(io.Writer) bool { return false }
+	t.Setenv("NO_COLOR", "")
+	got = sprint(Player{Name: "Al", Score: 11})
+	if got != want {
+		t.Errorf("not a terminal: got %q, want %q", got, want)
+	}
+}
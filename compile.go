@@ -0,0 +1,42 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"io"
+	"reflect"
+)
+
+// Compile returns a function that formats values of type T as
+// f.Fprint does. It is for types printed repeatedly, as in a loop.
+// The function uses a copy of f, so later changes to f don't affect it.
+// The struct types that T contains have their fields resolved once,
+// including their order under FieldOrder, instead of on every call.
+func Compile[T any](f *Formatter) func(w io.Writer, v T) error {
+	c := f.Clone()
+	c.plans = map[reflect.Type][]fieldPlan{}
+	seen := map[reflect.Type]bool{}
+	var resolve func(reflect.Type)
+	resolve = func(t reflect.Type) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Chan:
+			resolve(t.Elem())
+		case reflect.Map:
+			resolve(t.Key())
+			resolve(t.Elem())
+		case reflect.Struct:
+			plan := c.fieldPlans(t)
+			c.plans[t] = plan
+			for _, fp := range plan {
+				resolve(fp.field.Type)
+			}
+		}
+	}
+	resolve(reflect.TypeFor[T]())
+	return func(w io.Writer, v T) error { return c.Fprint(w, v) }
+}
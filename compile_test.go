@@ -0,0 +1,48 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	f := New(WithOmitPackage(true))
+	f.FieldOrder = FieldsByName
+	tm := &team{Players: []Player{{Name: "Al", Score: 1}}, M: map[string]int{"a": 1}}
+	want := f.Sprint(tm)
+	fprint := Compile[*team](f)
+	// Changes to f don't affect fprint.
+	f.FieldOrder = nil
+	f.IgnoreFields(Player{}, "Score")
+	var b strings.Builder
+	if err := fprint(&b, tm); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	players := make([]Player, 1000)
+	for i := range players {
+		players[i] = Player{Name: "p", Score: i}
+	}
+	f := New(WithCompact(true))
+	f.FieldOrder = FieldsByName
+	b.Run("Fprint", func(b *testing.B) {
+		for range b.N {
+			f.Fprint(io.Discard, players)
+		}
+	})
+	b.Run("Compile", func(b *testing.B) {
+		fprint := Compile[[]Player](f)
+		for range b.N {
+			fprint(io.Discard, players)
+		}
+	})
+}
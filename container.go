@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"container/list"
+	"container/ring"
+	"reflect"
+)
+
+var (
+	listType = reflect.TypeFor[list.List]()
+	ringType = reflect.TypeFor[ring.Ring]()
+)
+
+// isContainer reports whether values of type t are printed by printContainer.
+func isContainer(t reflect.Type) bool {
+	return t == listType || t == ringType
+}
+
+// printContainer prints a list.List or ring.Ring as the sequence of
+// its elements' values, like a slice:
+//
+//	list.List{1, 2, 3}
+//
+// A ring's elements start with the given one.
+// It reports whether it printed v.
+func (s *state) printContainer(v reflect.Value) bool {
+	if !isContainer(v.Type()) || !v.CanInterface() {
+		return false
+	}
+	var vals []reflect.Value
+	switch v.Type() {
+	case listType:
+		l := addressOf(v).Interface().(*list.List)
+		for e := l.Front(); e != nil; e = e.Next() {
+			vals = append(vals, reflect.ValueOf(e.Value))
+		}
+	case ringType:
+		if v.FieldByName("next").IsNil() {
+			// A zero Ring is a ring of one element. Don't call its methods,
+			// which would initialize it.
+			vals = append(vals, v.FieldByName("Value").Elem())
+		} else {
+			r := addressOf(v).Interface().(*ring.Ring)
+			if !v.CanAddr() {
+				// r is a copy, which is not part of the ring. Find the original.
+				r = r.Next().Prev()
+			}
+			r.Do(func(x any) {
+				vals = append(vals, reflect.ValueOf(x))
+			})
+		}
+	}
+	s.prType(v.Type())
+	s.prc(punctClass, "{")
+	s.printElements(len(vals), s.maxElements(v.Type()), s.TailElements, func(i int) reflect.Value { return vals[i] })
+	return true
+}
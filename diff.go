@@ -0,0 +1,267 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Diff calls [Formatter.Diff] with the default Formatter.
+func Diff(got, want any) string { return New().Diff(got, want) }
+
+// Diff compares got and want and returns a description of their differences,
+// or the empty string if there are none.
+// Each line of the result describes a single differing path, like
+//
+//	Players[1].Score: got 11, want 12
+//
+// Values are compared using the same rules that f uses for printing:
+// ignored and unexported fields are skipped, comparison stops at MaxDepth,
+// and cycles are detected.
+// Two values are considered equal if f prints them the same.
+func (f *Formatter) Diff(got, want any) string {
+	d := &differ{
+		Formatter:    f,
+		maxDepth:     f.maxDepth(),
+		seen:         map[[2]any]bool{},
+		transforming: map[reflect.Type]bool{},
+		depth:        -1,
+	}
+	d.diff(reflect.ValueOf(got), reflect.ValueOf(want))
+	return strings.Join(d.lines, "")
+}
+
+type differ struct {
+	*Formatter
+	maxDepth int
+	seen     map[[2]any]bool // pairs of pointers currently being compared
+	// The types whose transformers are being applied.
+	transforming map[reflect.Type]bool
+	path         Path
+	depth        int
+	lines        []string
+}
+
+func (d *differ) diff(v1, v2 reflect.Value) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > d.maxDepth {
+		return
+	}
+	d.diffSameDepth(v1, v2)
+}
+
+// diffStep compares v1 and v2, the values at step from the current path.
+// If either is invalid, it is missing.
+func (d *differ) diffStep(step string, v1, v2 reflect.Value) {
+	d.path = append(d.path, step)
+	defer func() { d.path = d.path[:len(d.path)-1] }()
+	if !d.pathSelected(d.path) {
+		return
+	}
+	if !v1.IsValid() || !v2.IsValid() {
+		d.reportMissing(v1, v2)
+	} else {
+		d.diff(v1, v2)
+	}
+}
+
+func (d *differ) diffSameDepth(v1, v2 reflect.Value) {
+	if v1.IsValid() && v1.Kind() == reflect.Interface {
+		v1 = v1.Elem()
+	}
+	if v2.IsValid() && v2.Kind() == reflect.Interface {
+		v2 = v2.Elem()
+	}
+	if !v1.IsValid() || !v2.IsValid() || v1.Type() != v2.Type() {
+		d.report(v1, v2)
+		return
+	}
+	if d.ignoreTypes[v1.Type()] {
+		return
+	}
+	if d.redactTypes[v1.Type()] {
+		if d.differRedacted(v1, v2) {
+			r := d.markers().Redacted
+			d.addLine(r, r)
+		}
+		return
+	}
+	if fn := d.transformer(v1, d.transforming); fn != nil && v2.CanInterface() {
+		d.transforming[v1.Type()] = true
+		defer delete(d.transforming, v1.Type())
+		var t1, t2 reflect.Value
+		err1 := catch(func() { t1 = fn(v1) })
+		err2 := catch(func() { t2 = fn(v2) })
+		if err1 != nil || err2 != nil {
+			// The values can't be compared. Show them, with the panic
+			// printed as by Sprint.
+			d.addLine(d.sprintCompact(v1), d.sprintCompact(v2))
+			return
+		}
+		d.diffSameDepth(t1, t2)
+		return
+	}
+	if _, ok, _ := d.customString(v1); ok {
+		d.report(v1, v2)
+		return
+	}
+	switch v1.Kind() {
+	case reflect.Pointer:
+		if v1.IsNil() || v2.IsNil() {
+			if v1.IsNil() != v2.IsNil() {
+				d.report(v1, v2)
+			}
+			return
+		}
+		if v1.Pointer() == v2.Pointer() {
+			return
+		}
+		key := [2]any{v1.Interface(), v2.Interface()}
+		if d.seen[key] {
+			return
+		}
+		d.seen[key] = true
+		defer delete(d.seen, key)
+		d.diffSameDepth(v1.Elem(), v2.Elem())
+
+	case reflect.Array, reflect.Slice:
+		if d.ShowNil && v1.Kind() == reflect.Slice && v1.IsNil() != v2.IsNil() {
+			d.report(v1, v2)
+			return
+		}
+		index1, index2 := d.elementIndex(v1), d.elementIndex(v2)
+		n := max(v1.Len(), v2.Len())
+		for i := range n {
+			var e1, e2 reflect.Value
+			if i < v1.Len() {
+				e1 = index1(i)
+			}
+			if i < v2.Len() {
+				e2 = index2(i)
+			}
+			d.diffStep("["+strconv.Itoa(i)+"]", e1, e2)
+		}
+
+	case reflect.Map:
+		if d.ShowNil && v1.IsNil() != v2.IsNil() {
+			d.report(v1, v2)
+			return
+		}
+		// Entries whose keys can't be looked up, like NaNs, are always
+		// reported as missing from the other map.
+		type pair struct{ key, v1, v2 reflect.Value }
+		var pairs []pair
+		// The pairs are sorted below, so the order of the entries doesn't matter.
+		es1, _ := mapEntries(v1)
+		es2, _ := mapEntries(v2)
+		for _, e := range es1 {
+			pairs = append(pairs, pair{e.key, e.val, v2.MapIndex(e.key)})
+		}
+		for _, e := range es2 {
+			if !v1.MapIndex(e.key).IsValid() {
+				pairs = append(pairs, pair{e.key, reflect.Value{}, e.val})
+			}
+		}
+		// If MapKeyOrder panics, the pairs are in no particular order.
+		_ = catch(func() { slices.SortStableFunc(pairs, func(p1, p2 pair) int { return d.compareKeys(p1.key, p2.key) }) })
+		for _, p := range pairs {
+			d.diffStep(keyStep(d.Formatter, p.key), p.v1, p.v2)
+		}
+
+	case reflect.Struct:
+		t := v1.Type()
+		ignore := d.ignoreFields[t]
+		redact := d.redactFields[t]
+		for _, fp := range d.fieldPlans(t) {
+			sf := fp.field
+			if !fp.exported || d.ignoredField(ignore, sf.Name) {
+				continue
+			}
+			i := fp.index
+			// A field is kept if a FilterFields function panics.
+			keep1, _ := d.keepField(sf, v1.Field(i))
+			keep2, _ := d.keepField(sf, v2.Field(i))
+			if !keep1 && !keep2 {
+				continue
+			}
+			if fp.tag.redact || slices.Contains(redact, sf.Name) {
+				// Report a difference without revealing the values.
+				if d.differRedacted(v1.Field(i), v2.Field(i)) {
+					r := d.markers().Redacted
+					d.path = append(d.path, sf.Name)
+					d.addLine(r, r)
+					d.path = d.path[:len(d.path)-1]
+				}
+				continue
+			}
+			d.diffStep(sf.Name, v1.Field(i), v2.Field(i))
+		}
+
+	default:
+		if d.sprintCompact(v1) != d.sprintCompact(v2) {
+			d.report(v1, v2)
+		}
+	}
+}
+
+// differRedacted reports whether the redacted values v1 and v2 differ.
+func (d *differ) differRedacted(v1, v2 reflect.Value) bool {
+	c := d.Clone()
+	c.redactFields = nil
+	c.redactTypes = nil
+	return c.Diff(v1.Interface(), v2.Interface()) != ""
+}
+
+// report records a difference at the current path,
+// unless the values print the same.
+func (d *differ) report(v1, v2 reflect.Value) {
+	s1, s2 := d.sprintCompact(v1), d.sprintCompact(v2)
+	if s1 == s2 {
+		if !v1.IsValid() || !v2.IsValid() || v1.Type() == v2.Type() {
+			return
+		}
+		// Same representation, different types: show the types.
+		s1 = fmt.Sprintf("%s(%s)", d.typeName(v1.Type()), s1)
+		s2 = fmt.Sprintf("%s(%s)", d.typeName(v2.Type()), s2)
+	}
+	d.addLine(s1, s2)
+}
+
+// reportMissing records a difference at the current path where one of the values
+// is absent, as for a slice element or map entry.
+func (d *differ) reportMissing(v1, v2 reflect.Value) {
+	s1, s2 := "<missing>", "<missing>"
+	if v1.IsValid() {
+		s1 = d.sprintCompact(v1)
+	}
+	if v2.IsValid() {
+		s2 = d.sprintCompact(v2)
+	}
+	d.addLine(s1, s2)
+}
+
+func (d *differ) addLine(got, want string) {
+	var prefix string
+	if len(d.path) > 0 {
+		prefix = d.path.String() + ": "
+	}
+	d.lines = append(d.lines, fmt.Sprintf("%sgot %s, want %s\n", prefix, got, want))
+}
+
+// sprintCompact formats v on a single line, using f's other settings.
+func (f *Formatter) sprintCompact(v reflect.Value) string {
+	c := *f
+	c.Compact = true
+	c.MaxWidth = 0
+	c.Prefix = ""
+	c.HeaderFunc = nil
+	c.ignorePaths = nil
+	c.onlyPaths = nil
+	return sprintBuf(func(b []byte) []byte { return c.appendValue(b, v, nil) })
+}
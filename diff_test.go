@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	for _, test := range []struct {
+		f         Formatter
+		got, want any
+		wantDiff  string
+	}{
+		{got: 1, want: 1, wantDiff: ""},
+		{got: 1, want: 2, wantDiff: "got 1, want 2\n"},
+		{got: 1, want: int64(1), wantDiff: "got int(1), want int64(1)\n"},
+		{
+			f:        *New(WithTransform(func(p Player) string { return strings.ToLower(p.Name) })),
+			got:      []Player{{Name: "Al", Score: 1}, {Name: "Bo"}},
+			want:     []Player{{Name: "al", Score: 2}, {Name: "Cy"}},
+			wantDiff: "[1]: got \"bo\", want \"cy\"\n",
+		},
+		{
+			// A panicking transformer is reported, not propagated.
+			f: *New(WithTransform(func(p Player) string {
+				if p.Name == "" {
+					panic("no name")
+				}
+				return p.Name
+			})),
+			got:      []Player{{Name: "Al"}, {}},
+			want:     []Player{{Name: "Al"}, {Name: "Bo"}},
+			wantDiff: "[1]: got <panic: no name>, want \"Bo\"\n",
+		},
+		{
+			f:        *New(WithRedact(Player{}, "Name"), WithRedactTypes(time.Duration(0))),
+			got:      []any{Player{Name: "Al"}, time.Second, time.Minute},
+			want:     []any{Player{Name: "Bo"}, time.Second, time.Hour},
+			wantDiff: "[0].Name: got <redacted>, want <redacted>\n[2]: got <redacted>, want <redacted>\n",
+		},
+		{
+			got:      map[float64]int{math.NaN(): 1, 2: 2},
+			want:     map[float64]int{math.NaN(): 1, 2: 3},
+			wantDiff: "[NaN]: got 1, want <missing>\n[NaN]: got <missing>, want 1\n[2]: got 2, want 3\n",
+		},
+		{
+			got:      Player{"Al", 11, true},
+			want:     Player{"Al", 12, false},
+			wantDiff: "Score: got 11, want 12\n",
+		},
+		{
+			got:      []int{1, 2, 3},
+			want:     []int{1, 5},
+			wantDiff: "[1]: got 2, want 5\n[2]: got 3, want <missing>\n",
+		},
+		{
+			got:      map[string]int{"a": 1, "b": 2},
+			want:     map[string]int{"b": 3, "c": 4},
+			wantDiff: "[\"a\"]: got 1, want <missing>\n[\"b\"]: got 2, want 3\n[\"c\"]: got <missing>, want 4\n",
+		},
+		{
+			got:      &node{1, &node{2, nil}},
+			want:     &node{1, &node{3, nil}},
+			wantDiff: "Next.I: got 2, want 3\n",
+		},
+		{
+			f: func() Formatter {
+				var f Formatter
+				f.IgnoreFields(node{}, "Next")
+				return f
+			}(),
+			got:      &node{1, &node{2, nil}},
+			want:     &node{1, &node{3, nil}},
+			wantDiff: "",
+		},
+		{
+			got: func() any {
+				n := &node{I: 1}
+				n.Next = n
+				return n
+			}(),
+			want: func() any {
+				n := &node{I: 1}
+				n.Next = n
+				return n
+			}(),
+			wantDiff: "",
+		},
+		{
+			got:      tagged{Omit: 1, Secret: "a"},
+			want:     tagged{Omit: 2, Secret: "b"},
+			wantDiff: "Secret: got <redacted>, want <redacted>\n",
+		},
+		{
+			got:      []int{},
+			want:     []int(nil),
+			wantDiff: "",
+		},
+		{
+			f:        Formatter{ShowNil: true},
+			got:      []int{},
+			want:     []int(nil),
+			wantDiff: "got []{}, want nil\n",
+		},
+	} {
+		test.f.OmitPackage = true
+		got := test.f.Diff(test.got, test.want)
+		if got != test.wantDiff {
+			t.Errorf("Diff(%v, %v):\ngot\n%s\nwant\n%s", test.got, test.want, got, test.wantDiff)
+		}
+	}
+}
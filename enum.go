@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"cmp"
+	"fmt"
+	"math/bits"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// RegisterEnum causes f to print values of type T by their names,
+// so that constants print as StatusActive instead of 1.
+// A value without a name prints as its type and number, like Status(7).
+// It returns f.
+func RegisterEnum[T comparable](f *Formatter, names map[T]string) *Formatter {
+	return f.setPrinter(reflect.TypeFor[T](), func(f *Formatter, v reflect.Value) string {
+		if name, ok := names[v.Interface().(T)]; ok {
+			return name
+		}
+		return f.unnamed(v)
+	})
+}
+
+// An integer is a type with an integer kind.
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// RegisterFlags causes f to print values of type T, a set of bit flags,
+// as the names of the flags that are set, separated by "|",
+// like FlagA|FlagC.
+// Each key of names should have one bit set, or several for a name that
+// combines other flags. Combinations are used when possible.
+// Bits without names print as a hex number, as in FlagA|0x40.
+// Zero prints as its name if it has one, and as 0 otherwise.
+// It returns f.
+func RegisterFlags[T integer](f *Formatter, names map[T]string) *Formatter {
+	flags := make([]T, 0, len(names))
+	for x := range names {
+		if x != 0 {
+			flags = append(flags, x)
+		}
+	}
+	// Try flags with more bits first, so that combinations are preferred.
+	slices.SortFunc(flags, func(a, b T) int {
+		return cmp.Or(bits.OnesCount64(uint64(b))-bits.OnesCount64(uint64(a)), cmp.Compare(a, b))
+	})
+	return FormatFunc(f, func(x T) string {
+		if x == 0 {
+			if name, ok := names[0]; ok {
+				return name
+			}
+			return "0"
+		}
+		var set []T
+		rest := x
+		for _, fl := range flags {
+			if rest&fl == fl {
+				set = append(set, fl)
+				rest &^= fl
+			}
+		}
+		slices.Sort(set)
+		var parts []string
+		for _, fl := range set {
+			parts = append(parts, names[fl])
+		}
+		if rest != 0 {
+			parts = append(parts, fmt.Sprintf("%#x", uint64(rest)))
+		}
+		return strings.Join(parts, "|")
+	})
+}
+
+// unnamed formats a value of an enum type that has no name.
+func (f *Formatter) unnamed(v reflect.Value) string {
+	var s string
+	switch {
+	case v.CanInt():
+		s = formatInt(v.Int(), 10)
+	case v.CanUint():
+		s = formatUint(v.Uint(), 10)
+	default:
+		s = fmt.Sprintf("%#v", v.Interface())
+	}
+	return f.typeName(v.Type()) + "(" + s + ")"
+}
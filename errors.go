@@ -0,0 +1,44 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "reflect"
+
+// printError prints v, if it is an error, with its message and the
+// errors it wraps:
+//
+//	*fmt.wrapError("read config: EOF"){*errors.errorString("EOF")}
+//
+// An error that wraps several, like one returned by errors.Join,
+// lists them all. It reports whether v is an error.
+func (s *state) printError(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface || !v.CanInterface() || s.noMethods[v.Type()] ||
+		(v.Kind() == reflect.Pointer && v.IsNil()) {
+		return false
+	}
+	err, ok := v.Interface().(error)
+	if !ok {
+		return false
+	}
+	// Call the methods before printing, in case they panic.
+	msg := err.Error()
+	var wrapped []error
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		if w := u.Unwrap(); w != nil {
+			wrapped = []error{w}
+		}
+	case interface{ Unwrap() []error }:
+		wrapped = u.Unwrap()
+	}
+	s.prType(v.Type())
+	s.prc(punctClass, "(")
+	s.printString(msg)
+	s.prc(punctClass, ")")
+	if len(wrapped) > 0 {
+		s.prc(punctClass, "{")
+		s.printElements(len(wrapped), s.maxElements(v.Type()), s.TailElements, func(i int) reflect.Value { return reflect.ValueOf(wrapped[i]) })
+	}
+	return true
+}
@@ -2,8 +2,9 @@
 // Use of this source code is governed by the license in the LICENSE file.
 
 // TODO: unexported values; https://stackoverflow.com/questions/42664837/how-to-access-unexported-struct-fields/43918797#43918797
+// When ShowUnexported is added, also add IgnoreUnexportedIn(types...) to hide
+// the unexported fields of particular types, like cmpopts.IgnoreUnexported.
 // TODO: doc
-// TODO: named slice/array/map types
 // TODO: unnamed struct types
 
 package format
@@ -11,12 +12,23 @@ package format
 import (
 	"bytes"
 	"cmp"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"math"
 	"os"
+	"path"
 	"reflect"
+	"regexp"
+	"runtime"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"unsafe"
 )
 
 // A Formatter formats Go values.
@@ -24,21 +36,149 @@ import (
 // Configure a Formatter by setting the exported fields before
 // calling a formatting method.
 // The defaults are designed to work well in tests.
+//
+// A Formatter's methods never modify it, other than the ones that configure it,
+// like [Formatter.IgnoreFields].
+// Once configured, a Formatter is safe for concurrent use by multiple goroutines.
+//
+// With GoSyntax set, the output can be pasted into Go source,
+// unless it was truncated by MaxDepth or MaxElements, or contains a cycle.
+//
+// With Deterministic set, formatting equal values gives the same output
+// in every run, so it can be compared to a golden file. Map entries are
+// sorted, with NaN keys ordered by their values. The exceptions are values
+// printed by methods or registered functions that vary, and map keys that
+// are channels or funcs, or that contain them, which are ordered by address.
+//
+// With Parallel set, the elements of a large top-level slice or array are
+// formatted by several goroutines at once. Then the code the Formatter
+// calls must be safe for concurrent use: functions passed to [FormatFunc],
+// [Transform] and [Formatter.FilterFields], MapKeyOrder and the other
+// function-valued fields, and methods like String and IsZero.
+// A panic in a goroutine that the Formatter doesn't recover, as it does
+// panics in String methods, is repanicked in the goroutine that called
+// the formatting method.
 type Formatter struct {
 	// ShowUnexported bool   // display unexported fields
-	ShowZero     bool   // display struct fields that have their zero value
-	MaxWidth     int    // maximum columns, but not breaking words
-	Compact      bool   // as few lines as possible, observing MaxWidth
-	Indent       string // ignored if Compact; default is 4 spaces
-	MaxDepth     int    // max recursion depth; default is 100
-	MaxElements  int    // max array, slice or map elements to print
-	OmitPackage  bool   // don't print package in type names
-	ignoreFields map[reflect.Type][]string
+	ShowZero         bool             // display struct fields that have their zero value
+	MaxWidth         int              // maximum columns, but not breaking words
+	Compact          bool             // as few lines as possible, observing MaxWidth
+	Indent           string           // default is 4 spaces; if Compact, used for continuation lines
+	TabWidth         int              // columns between tab stops, for MaxWidth and TabAlign; default is 8
+	IndentGuides     bool             // begin each level of indentation with a vertical line, as in "│   "
+	MaxDepth         int              // max recursion depth; default is 100
+	MaxElements      int              // max array, slice or map elements to print; see also MaxElementsFor
+	ChunkSize        int              // with Fprint, write output in pieces of about this many bytes as it is formatted
+	Parallel         int              // format a top-level slice or array with at least this many elements in concurrent chunks
+	SoftDepth        int              // print structs, arrays, slices and maps nested more deeply than this as their size, like T{...3 fields}
+	TailElements     int              // with MaxElements, also print this many elements from the end, after a marker like ...(+5)
+	OmitPackage      bool             // don't print package in type names
+	UseStringer      bool             // format a fmt.Stringer with its String method
+	UseGoStringer    bool             // format a fmt.GoStringer with its GoString method
+	UseError         bool             // format an error with its Error method
+	ErrorTree        bool             // format an error with its Error method and the errors it wraps; overrides UseError
+	GoSyntax         bool             // output valid Go syntax, as far as possible
+	Color            bool             // colorize output with ANSI escapes when writing to a terminal
+	Theme            *Theme           // colors to use; default is DefaultTheme
+	Markers          Markers          // placeholders for values that aren't printed
+	Strict           bool             // make Fprint and Print return a *StrictError if values aren't printed in full
+	AssumeAcyclic    bool             // don't check for pointer cycles, which is faster; a cycle is printed to MaxDepth
+	ShowSharing      bool             // label pointers reached more than once, as #1=&T{...} and later #1
+	MaxStringLen     int              // max bytes of a string to print
+	BytesMode        BytesMode        // how to print byte slices and arrays
+	Smart            bool             // print a value on one line if it fits in MaxWidth (default 80); ignored if Compact
+	ShowLen          bool             // show the lengths and capacities of slices and maps
+	ShowAddresses    bool             // show the addresses of pointers, channels and functions, as &0xc000010000 T{...}
+	PullSeqs         bool             // print iterator functions, like iter.Seq, as the values they yield, up to MaxElements or 1000
+	OrderedMaps      bool             // print structs with methods Keys and Get, or Oldest, as maps in their own order; calls those methods
+	PointerLabels    bool             // label pointers p#1, p#2, ... in order of appearance, as &p#1 T{...}, instead of showing addresses
+	Deterministic    bool             // print nothing that varies between runs: label pointers as with PointerLabels, and never show addresses
+	ShowNil          bool             // print nil slices and maps as nil, and nil pointers as (*T)(nil)
+	ShowDynamicTypes bool             // print the types of values stored in interfaces, as any(int) 1
+	MaxBytes         int              // stop after about this many bytes of output
+	MaxLines         int              // stop after this many lines of output
+	Prefix           string           // write at the start of every line; MaxWidth includes it
+	TimeFormat       string           // layout for time.Time; default is time.RFC3339Nano
+	RawTime          bool             // print time.Time and time.Duration like other structs and integers
+	RawSync          bool             // print sync.Map and the sync/atomic types like other structs
+	RawSets          bool             // print maps whose values are all true or struct{}{} like other maps, not as set{k1, k2}
+	WidthFunc        func(string) int // columns a string occupies, for MaxWidth; default counts runes
+	Table            bool             // print a slice of structs or maps as a table, one row per element
+	IntBase          int              // base for integers: 2, 8, 10 or 16; default is 10
+	FloatFormat      byte             // strconv.FormatFloat format for floats, like 'f' or 'e'; default is 'g'
+	FloatPrecision   int              // digits for FloatFormat; default is the fewest that represent the value exactly
+	FloatTolerance   float64          // print floats closer than this to zero as 0
+	Multiline        MultilineMode    // how to print strings with newlines
+	StringEscape     EscapeMode       // how to print strings with invalid UTF-8 or non-printable characters
+	AutoHex          bool             // print integers whose type names contain "Flags" or "Mask" in hex
+	TypeNames        TypeNameMode     // how to qualify the names of types defined in packages
+	LocalPackage     string           // import path of the package whose types TypeNameLocal leaves unqualified
+	AlignFields      bool             // pad struct field names and map keys so their values line up; ignored if Compact
+	TabAlign         bool             // align with tabs, as text/tabwriter does, instead of spaces: values with AlignFields, and table columns
+	TrailingCommas   bool             // end every field line with a comma, as gofmt does; ignored if Compact
+	PathComments     bool             // follow each field or element that fits on a line with a comment holding its path; ignored if Compact
+	Flatten          bool             // print the fields of embedded structs as fields of the enclosing struct
+	UseIsZero        bool             // treat a struct field as zero if its IsZero method returns true
+	JSONNames        bool             // name fields by their encoding/json tags, omitting "-" fields and zero omitempty fields
+
+	// TypeNameFunc, if non-nil, returns the name to print for a type,
+	// or "" to name it according to OmitPackage and TypeNames.
+	// It is passed each type whose name is printed, not the types
+	// that make up a composite type like []*T.
+	TypeNameFunc func(reflect.Type) string
+
+	// HeaderFunc, if non-nil, is called each time a value is formatted
+	// with a method like Sprint. Its result is written on a line before
+	// the value, after Prefix. Use it to label the output, for example
+	// with a request ID.
+	HeaderFunc func() string
+
+	// FieldOrder, if non-nil, orders the fields of a struct, like a
+	// comparison function for slices.SortFunc. FieldsByName orders them
+	// alphabetically. If nil, fields appear in declaration order.
+	FieldOrder func(a, b reflect.StructField) int
+
+	// MapKeyOrder, if non-nil, orders the keys of maps, like a comparison
+	// function for slices.SortFunc. Keys it considers equal are ordered
+	// as they are when it is nil: numbers numerically, strings
+	// lexically, and other values structurally. It doesn't apply to
+	// ordered maps, whose entries are printed in their own order.
+	MapKeyOrder func(a, b reflect.Value) int
+
+	// FieldNameFunc, if non-nil, returns the name to print for an exported
+	// struct field, or "" to omit the field. It overrides JSONNames.
+	// It is ignored with GoSyntax.
+	FieldNameFunc func(reflect.StructField) string
+
+	ignoreFields  map[reflect.Type][]string
+	printers      map[reflect.Type]func(*Formatter, reflect.Value) string
+	ifacePrinters []ifacePrinter
+	transforms    map[reflect.Type]func(reflect.Value) reflect.Value
+	noMethods     map[reflect.Type]bool
+	ignoreTypes   map[reflect.Type]bool
+	ignorePaths   []Path
+	onlyPaths     []Path
+	redactFields  map[reflect.Type][]string
+	redactTypes   map[reflect.Type]bool
+	zeroFields    map[reflect.Type][]string
+	zeroTypes     map[reflect.Type]bool
+	typeElems     map[reflect.Type]int
+	kindElems     map[reflect.Kind]int
+	typeDepths    map[reflect.Type]int
+	fieldFilters  []func(reflect.StructField, reflect.Value) bool
+	fieldPatterns []string
+	sortElems     map[reflect.Type]func(a, b reflect.Value) int
+	plans         map[reflect.Type][]fieldPlan // resolved by Compile
 }
 
-// New returns a new default Formatter.
-func New() *Formatter {
-	return &Formatter{}
+// New returns a new Formatter configured with opts.
+// With no options, it is the default Formatter.
+func New(opts ...Option) *Formatter {
+	f := &Formatter{}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
 }
 
 // IgnoreFields causes f to skip printing of the named fields of values of structval's type.
@@ -59,240 +199,2027 @@ func (f *Formatter) IgnoreFields(structval any, fields ...string) *Formatter {
 	return f
 }
 
+// IgnoreFieldPattern causes f to skip the fields of every struct type
+// whose names match one of patterns, in the syntax of [path.Match],
+// like "XXX_*". Unexported fields are matched as well.
+// It panics if a pattern is malformed.
+// It returns f.
+func (f *Formatter) IgnoreFieldPattern(patterns ...string) *Formatter {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			panic(fmt.Sprintf("IgnoreFieldPattern: malformed pattern %q", p))
+		}
+	}
+	f.fieldPatterns = append(f.fieldPatterns, patterns...)
+	return f
+}
+
+// ignoredField reports whether to skip the field with the given name,
+// because it is one of ignore, the fields ignored in its struct type,
+// or it matches a pattern passed to IgnoreFieldPattern.
+func (f *Formatter) ignoredField(ignore []string, name string) bool {
+	if slices.Contains(ignore, name) {
+		return true
+	}
+	for _, p := range f.fieldPatterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterFields causes f to print a struct field only if keep returns true
+// for the field and its value. It is a general way to omit fields: by
+// tag, by type, by name or by value, like empty slices even with ShowZero.
+// Fields omitted for other reasons, like IgnoreFields, are not passed to keep,
+// nor are unexported fields or embedded structs printed with Flatten.
+// If FilterFields is called more than once, a field must be kept by every
+// function to be printed. If keep panics, the field is printed with the
+// panic as its value. Diff skips a field only if keep returns false
+// for both of its values. In a table, the cells of omitted fields are empty.
+// It returns f.
+func (f *Formatter) FilterFields(keep func(reflect.StructField, reflect.Value) bool) *Formatter {
+	f.fieldFilters = append(f.fieldFilters, keep)
+	return f
+}
+
+// keepField reports whether the functions passed to FilterFields
+// keep the field sf, whose value is v.
+// If one panics, it returns the panic as err, and keep is true.
+func (f *Formatter) keepField(sf reflect.StructField, v reflect.Value) (keep bool, err error) {
+	if len(f.fieldFilters) == 0 {
+		return true, nil
+	}
+	keep = true
+	err = catch(func() {
+		for _, fn := range f.fieldFilters {
+			if !fn(sf, v) {
+				keep = false
+				return
+			}
+		}
+	})
+	return keep, err
+}
+
+// FormatFunc causes f to format values of type T by calling fn, instead of
+// formatting them according to their kind.
+// The string fn returns is written as is.
+// T must be the exact type of the value; values whose type merely
+// implements an interface T are not affected.
+// It returns f.
+func FormatFunc[T any](f *Formatter, fn func(T) string) *Formatter {
+	return f.setPrinter(reflect.TypeFor[T](), func(_ *Formatter, v reflect.Value) string {
+		return fn(v.Interface().(T))
+	})
+}
+
+// FormatInterface causes f to format values whose types implement I,
+// which must be an interface type, by calling fn.
+// A function registered with FormatFunc for a value's exact type takes
+// precedence. If a value implements several interfaces registered with
+// FormatInterface, the first one registered is used.
+// It returns f.
+func FormatInterface[I any](f *Formatter, fn func(I) string) *Formatter {
+	t := reflect.TypeFor[I]()
+	if t.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("%s is not an interface type", t))
+	}
+	f.ifacePrinters = append(f.ifacePrinters, ifacePrinter{t, func(v reflect.Value) string {
+		return fn(v.Interface().(I))
+	}})
+	return f
+}
+
+// An ifacePrinter formats the values whose types implement iface.
+type ifacePrinter struct {
+	iface reflect.Type
+	fn    func(reflect.Value) string
+}
+
+// setPrinter registers fn to format values of type t, and returns f.
+// fn is passed the Formatter in use, which may be a copy of f.
+func (f *Formatter) setPrinter(t reflect.Type, fn func(*Formatter, reflect.Value) string) *Formatter {
+	if f.printers == nil {
+		f.printers = map[reflect.Type]func(*Formatter, reflect.Value) string{}
+	}
+	f.printers[t] = fn
+	return f
+}
+
+// Transform causes f to convert values of type T by calling fn, and then
+// format the result instead of the original value.
+// Use it to replace a value with a more readable or more concise one,
+// like a protocol buffer timestamp with a time.Time, or a large matrix
+// with its dimensions.
+// As with FormatFunc, T must be the exact type of the value.
+// A transformer is not applied to values within its own result,
+// so fn may return a value that contains a T.
+// It returns f.
+func Transform[T, U any](f *Formatter, fn func(T) U) *Formatter {
+	if f.transforms == nil {
+		f.transforms = map[reflect.Type]func(reflect.Value) reflect.Value{}
+	}
+	f.transforms[reflect.TypeFor[T]()] = func(v reflect.Value) reflect.Value {
+		return reflect.ValueOf(fn(v.Interface().(T)))
+	}
+	return f
+}
+
+// transformer returns the function registered with [Transform] for v's type,
+// or nil if there is none or it is one of the active ones, which are being applied.
+func (f *Formatter) transformer(v reflect.Value, active map[reflect.Type]bool) func(reflect.Value) reflect.Value {
+	if fn := f.transforms[v.Type()]; fn != nil && !active[v.Type()] && v.CanInterface() {
+		return fn
+	}
+	return nil
+}
+
+// IgnoreTypes causes f to print a placeholder instead of any value
+// of the same type as one of vals.
+// It returns its receiver.
+func (f *Formatter) IgnoreTypes(vals ...any) *Formatter {
+	if f.ignoreTypes == nil {
+		f.ignoreTypes = map[reflect.Type]bool{}
+	}
+	for _, v := range vals {
+		f.ignoreTypes[reflect.TypeOf(v)] = true
+	}
+	return f
+}
+
+// Redact causes f to print the named fields of structval's type as
+// "<redacted>", instead of their values.
+// Unlike ignored fields, redacted fields show that a value was present.
+// Redacting a field has the same effect as the "redact" struct tag option.
+// It returns f.
+func (f *Formatter) Redact(structval any, fields ...string) *Formatter {
+	t := reflect.TypeOf(structval)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("%#v is not a struct or pointer to struct", structval))
+	}
+	if f.redactFields == nil {
+		f.redactFields = map[reflect.Type][]string{}
+	}
+	f.redactFields[t] = append(f.redactFields[t], fields...)
+	return f
+}
+
+// RedactTypes causes f to print values of the same types as vals
+// as "<redacted>".
+// It returns f.
+func (f *Formatter) RedactTypes(vals ...any) *Formatter {
+	if f.redactTypes == nil {
+		f.redactTypes = map[reflect.Type]bool{}
+	}
+	for _, v := range vals {
+		f.redactTypes[reflect.TypeOf(v)] = true
+	}
+	return f
+}
+
+// ShowZeroFields causes f to print the named fields of structval's type
+// even when they are zero, as if ShowZero were set for them alone.
+// The "omitzero" struct tag option still omits a field.
+// Structval must be a struct or a pointer to a struct.
+// It returns f.
+func (f *Formatter) ShowZeroFields(structval any, fields ...string) *Formatter {
+	t := reflect.TypeOf(structval)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("%#v is not a struct or pointer to struct", structval))
+	}
+	if f.zeroFields == nil {
+		f.zeroFields = map[reflect.Type][]string{}
+	}
+	f.zeroFields[t] = append(f.zeroFields[t], fields...)
+	return f
+}
+
+// ShowZeroTypes causes f to print struct fields of the same types as vals
+// even when they are zero, as if ShowZero were set for them alone.
+// The "omitzero" struct tag option still omits a field.
+// It returns f.
+func (f *Formatter) ShowZeroTypes(vals ...any) *Formatter {
+	if f.zeroTypes == nil {
+		f.zeroTypes = map[reflect.Type]bool{}
+	}
+	for _, v := range vals {
+		f.zeroTypes[reflect.TypeOf(v)] = true
+	}
+	return f
+}
+
+// MaxElementsFor sets the maximum number of elements to print for
+// values of the same type as val, overriding MaxElements.
+// If val is a [reflect.Kind], like reflect.Map, it applies to values of
+// that kind instead, unless there is a limit for their type.
+// As with MaxElements, n <= 0 means no limit.
+// It returns its receiver.
+func (f *Formatter) MaxElementsFor(val any, n int) *Formatter {
+	if k, ok := val.(reflect.Kind); ok {
+		if f.kindElems == nil {
+			f.kindElems = map[reflect.Kind]int{}
+		}
+		f.kindElems[k] = n
+		return f
+	}
+	if f.typeElems == nil {
+		f.typeElems = map[reflect.Type]int{}
+	}
+	f.typeElems[reflect.TypeOf(val)] = n
+	return f
+}
+
+// MaxDepthFor sets the maximum depth of the values within a value of
+// structval's type, counting from that value, so that structs like
+// linked lists or error chains can be expanded to a depth of their own.
+// MaxDepth remains the outer bound: no value is printed more deeply
+// than it allows. Values of the type within such a value do not extend
+// the limit.
+// Structval must be a struct or a pointer to a struct.
+// It returns f.
+func (f *Formatter) MaxDepthFor(structval any, n int) *Formatter {
+	t := reflect.TypeOf(structval)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("%#v is not a struct or pointer to struct", structval))
+	}
+	if f.typeDepths == nil {
+		f.typeDepths = map[reflect.Type]int{}
+	}
+	f.typeDepths[t] = n
+	return f
+}
+
+// maxElements returns the maximum number of elements to print for
+// a value of type t, or 0 for no limit.
+func (f *Formatter) maxElements(t reflect.Type) int {
+	if n, ok := f.typeElems[t]; ok {
+		return n
+	}
+	if n, ok := f.kindElems[t.Kind()]; ok {
+		return n
+	}
+	return f.MaxElements
+}
+
+// Markers are the placeholders printed in place of values that are not shown.
+// An empty field means the default, shown in parentheses.
+type Markers struct {
+	Truncated string // omitted elements, bytes or output ("...")
+	MaxDepth  string // a value nested more deeply than MaxDepth ("<maxdepth>")
+	Cycle     string // a pointer to a value that is being printed ("<cycle>")
+	Redacted  string // a redacted value ("<redacted>")
+}
+
+// markers returns f.Markers, with defaults for its empty fields.
+func (f *Formatter) markers() Markers {
+	m := f.Markers
+	m.Truncated = cmp.Or(m.Truncated, "...")
+	m.MaxDepth = cmp.Or(m.MaxDepth, "<maxdepth>")
+	m.Cycle = cmp.Or(m.Cycle, "<cycle>")
+	m.Redacted = cmp.Or(m.Redacted, "<redacted>")
+	return m
+}
+
+// IgnoreMethods causes f to disregard UseStringer, UseGoStringer, UseError and ErrorTree
+// for values of the same types as vals.
+// Use it for types whose String or Error methods are unhelpful.
+// It returns its receiver.
+func (f *Formatter) IgnoreMethods(vals ...any) *Formatter {
+	if f.noMethods == nil {
+		f.noMethods = map[reflect.Type]bool{}
+	}
+	for _, v := range vals {
+		f.noMethods[reflect.TypeOf(v)] = true
+	}
+	return f
+}
+
+// customString returns the string for v produced by a function registered
+// with [FormatFunc] or, if so configured, by one of v's methods.
+// It reports whether there was such a string.
+// If the function or method panics, it returns the panic as err, and true.
+func (f *Formatter) customString(v reflect.Value) (str string, ok bool, err error) {
+	err = catch(func() { str, ok = f.customStringOrPanic(v) })
+	return str, ok || err != nil, err
+}
+
+func (f *Formatter) customStringOrPanic(v reflect.Value) (string, bool) {
+	if fn := f.printers[v.Type()]; fn != nil {
+		return fn(f, v), true
+	}
+	if v.Kind() != reflect.Interface && v.CanInterface() && !(v.Kind() == reflect.Pointer && v.IsNil()) {
+		for _, p := range f.ifacePrinters {
+			if v.Type().Implements(p.iface) {
+				return p.fn(v), true
+			}
+		}
+	}
+	if !f.RawTime && v.Kind() != reflect.Interface && v.CanInterface() {
+		switch x := v.Interface().(type) {
+		case time.Time:
+			layout := f.TimeFormat
+			if layout == "" {
+				layout = time.RFC3339Nano
+			}
+			return x.Format(layout), true
+		case time.Duration:
+			if !f.GoSyntax {
+				return x.String(), true
+			}
+		}
+	}
+	if str, ok := f.stdString(v); ok {
+		return str, true
+	}
+	if str, ok := f.reflectTypeString(v); ok {
+		return str, true
+	}
+	if !(f.UseStringer || f.UseGoStringer || f.UseError) ||
+		v.Kind() == reflect.Interface || !v.CanInterface() || f.noMethods[v.Type()] {
+		return "", false
+	}
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return "", false
+	}
+	x := v.Interface()
+	if g, ok := x.(fmt.GoStringer); ok && f.UseGoStringer {
+		return g.GoString(), true
+	}
+	if e, ok := x.(error); ok && f.UseError {
+		return e.Error(), true
+	}
+	if st, ok := x.(fmt.Stringer); ok && f.UseStringer {
+		return st.String(), true
+	}
+	return "", false
+}
+
+// catch calls fn. If fn panics, catch returns an error wrapping
+// ErrPanic that describes the panic.
+func catch(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// printPanic prints a marker for a function or method that panicked
+// with err, as returned by catch.
+func (s *state) printPanic(err error) {
+	s.problem(s.path, err)
+	s.prc(markerClass, "<"+err.Error()+">")
+}
+
 // Sprint calls [Formatter.Sprint] with the default Formatter.
-func Sprint(x any) string { return New().Sprint(x) }
+func Sprint(xs ...any) string { return New().Sprint(xs...) }
 
 // Print calls [Formatter.Print] with the default Formatter.
-func Print(x any) error { return New().Print(x) }
+func Print(xs ...any) error { return New().Print(xs...) }
 
 // Fprint calls [Formatter.Fprint] with the default Formatter.
-func Fprint(w io.Writer, x any) error { return New().Fprint(w, x) }
+func Fprint(w io.Writer, xs ...any) error { return New().Fprint(w, xs...) }
+
+// Append calls [Formatter.Append] with the default Formatter.
+func Append(dst []byte, x any) []byte { return New().Append(dst, x) }
+
+// AppendTo calls [Formatter.AppendTo] with the default Formatter.
+func AppendTo(buf *bytes.Buffer, x any) { New().AppendTo(buf, x) }
+
+// Dump, Sdump and Fdump are like Print, Sprint and Fprint,
+// but ignore errors. They match the functions of the go-spew package,
+// so this package can replace it.
+
+// Dump calls [Print] and ignores the error.
+func Dump(xs ...any) { _ = Print(xs...) }
+
+// Sdump calls [Sprint].
+func Sdump(xs ...any) string { return Sprint(xs...) }
+
+// Fdump calls [Fprint] and ignores the error.
+func Fdump(w io.Writer, xs ...any) { _ = Fprint(w, xs...) }
+
+// Sprint formats each of xs and returns a string.
+// Values are separated by newlines.
+func (f *Formatter) Sprint(xs ...any) string {
+	return sprintBuf(func(b []byte) []byte { return f.appendValues(b, xs, nil) })
+}
+
+// Append formats x, appends the result to dst and returns the extended buffer.
+// Output is not colored.
+func (f *Formatter) Append(dst []byte, x any) []byte {
+	return f.appendValue(dst, reflect.ValueOf(x), nil)
+}
+
+// AppendTo is like Append, but writes to buf. It formats x in the unused
+// capacity of buf, so reusing a buffer avoids allocating.
+func (f *Formatter) AppendTo(buf *bytes.Buffer, x any) {
+	buf.Write(f.Append(buf.AvailableBuffer(), x))
+}
+
+// Print formats each of xs and writes to the standard output.
+// Values are separated by newlines.
+func (f *Formatter) Print(xs ...any) error {
+	return f.Fprint(os.Stdout, xs...)
+}
+
+// Fprint formats each of xs and writes to w.
+// Values are separated by newlines.
+// With Strict, it returns a *StrictError if the values were written
+// but some were not printed in full.
+//
+// Fprint writes all its output at once, unless ChunkSize is positive
+// and neither Prefix nor HeaderFunc is set. Then it writes each piece of
+// about ChunkSize bytes as it is formatted, so the whole output need not
+// be held in memory. If w has a Flush method, like a [bufio.Writer],
+// Fprint calls it after each piece.
+func (f *Formatter) Fprint(w io.Writer, xs ...any) error {
+	out := &output{theme: f.theme(w), problems: new([]*Problem)}
+	var b []byte
+	if f.ChunkSize > 0 && f.Prefix == "" && f.HeaderFunc == nil {
+		out.w = &chunkWriter{w: w}
+		b = f.appendValues(nil, xs, out)
+		out.w.Write(b)
+		if out.w.err != nil {
+			return out.w.err
+		}
+	} else {
+		b = f.appendValues(nil, xs, out)
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	if len(*out.problems) > 0 {
+		return &StrictError{Problems: *out.problems}
+	}
+	return nil
+}
+
+// An output describes where formatted values go, beyond the buffer
+// they are appended to.
+type output struct {
+	theme    *Theme       // colors to use, or nil
+	problems *[]*Problem  // if non-nil, values not printed in full are added here
+	w        *chunkWriter // if non-nil, write the buffer here every ChunkSize bytes
+}
+
+// A chunkWriter writes pieces of output for ChunkSize.
+type chunkWriter struct {
+	w    io.Writer
+	last byte  // the last byte written
+	err  error // the first error from w
+}
+
+// Write writes b to w and flushes w if it can be flushed.
+// After an error, it does nothing.
+func (c *chunkWriter) Write(b []byte) {
+	if c.err != nil || len(b) == 0 {
+		return
+	}
+	if _, c.err = c.w.Write(b); c.err != nil {
+		return
+	}
+	c.last = b[len(b)-1]
+	if fl, ok := c.w.(interface{ Flush() error }); ok {
+		c.err = fl.Flush()
+	}
+}
 
-// Sprint formats x and returns a string.
-func (f *Formatter) Sprint(x any) string {
-	var buf bytes.Buffer
-	_ = f.Fprint(&buf, x)
-	return buf.String()
+// appendValues appends each of xs to dst, separated by newlines.
+// out may be nil.
+func (f *Formatter) appendValues(dst []byte, xs []any, out *output) []byte {
+	for i, x := range xs {
+		if i > 0 && !endsLine(dst, out) {
+			dst = append(dst, '\n')
+		}
+		dst = f.appendValue(dst, reflect.ValueOf(x), out)
+	}
+	return dst
 }
 
-// Print formats x and writes to the standard output.
-func (f *Formatter) Print(x any) error {
-	return f.Fprint(os.Stdout, x)
+// endsLine reports whether the output so far, including dst, is empty or
+// ends with a newline.
+func endsLine(dst []byte, out *output) bool {
+	if len(dst) > 0 {
+		return dst[len(dst)-1] == '\n'
+	}
+	return out == nil || out.w == nil || out.w.last == 0 || out.w.last == '\n'
 }
 
-// Fprint formats x and writes to w.
-func (f *Formatter) Fprint(w io.Writer, x any) error {
+// indent returns the indentation to use, observing the default.
+func (f *Formatter) indent() string {
 	if f.Indent == "" {
-		f.Indent = "    "
+		return "    "
 	}
+	return f.Indent
+}
+
+// maxWidth returns the maximum width to use, observing the default.
+// It excludes the width of Prefix.
+func (f *Formatter) maxWidth() int {
+	w := f.MaxWidth
+	if f.Smart && w <= 0 {
+		w = 80
+	}
+	if w > 0 && f.Prefix != "" {
+		w = max(w-f.width(f.Prefix), 1)
+	}
+	return w
+}
+
+// maxDepth returns the maximum depth to use, observing the default.
+func (f *Formatter) maxDepth() int {
 	if f.MaxDepth <= 0 {
-		f.MaxDepth = 100
+		return 100
+	}
+	return f.MaxDepth
+}
+
+// appendValue appends the formatted v to dst, as described by out,
+// which may be nil.
+func (f *Formatter) appendValue(dst []byte, v reflect.Value, out *output) []byte {
+	if f.Prefix == "" && f.HeaderFunc == nil {
+		return f.appendBody(dst, v, out)
+	}
+	var b []byte
+	if f.HeaderFunc != nil {
+		b = append(b, f.HeaderFunc()...)
+		b = append(b, '\n')
+	}
+	b = f.appendBody(b, v, out)
+	for len(b) > 0 {
+		line := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line = b[:i+1]
+		}
+		dst = append(dst, f.Prefix...)
+		dst = append(dst, line...)
+		b = b[len(line):]
+	}
+	return dst
+}
+
+// appendBody is like appendValue, but ignores Prefix and HeaderFunc.
+func (f *Formatter) appendBody(dst []byte, v reflect.Value, out *output) []byte {
+	if out == nil {
+		out = &output{}
+	}
+	if f.Table {
+		if b, ok := f.appendTable(dst, v); ok {
+			return b
+		}
+	}
+	var shared map[ptrKey]int
+	var pulled map[reflect.Value]pulledSeq
+	if f.ShowSharing {
+		// Count how often each pointer is reached by formatting
+		// without output.
+		s := f.newState()
+		s.discard = true
+		s.shared = map[ptrKey]int{}
+		s.print(v)
+		shared = s.shared
+		pulled = s.pulled // so iterators are called only once
+		s.free()
+	}
+	s := f.newState()
+	s.buf = dst
+	s.theme = out.theme
+	s.out = out.w
+	if shared != nil {
+		s.shared = shared
+		s.labels = map[ptrKey]int{}
+	}
+	if pulled != nil {
+		s.pulled = pulled
+	}
+	s.print(v)
+	if s.err == errBudget {
+		// Write the marker outside the budget.
+		s.err = nil
+		s.maxBytes, s.maxLines = 0, 0
+		s.writeClass(markerClass, f.markers().Truncated+"(truncated)")
+	}
+	if s.col != 0 && !f.Compact {
+		s.buf = append(s.buf, '\n')
+	}
+	if out.problems != nil {
+		*out.problems = append(*out.problems, s.problems...)
 	}
-	s := &state{
+	b := s.buf
+	s.free()
+	return b
+}
+
+// statePool holds unused states, to reuse their allocations.
+var statePool = sync.Pool{
+	New: func() any { return &state{seen: map[ptrKey]bool{}} },
+}
+
+// newState returns a state for formatting with f,
+// with f's defaults resolved.
+// Call free when done with it.
+func (f *Formatter) newState() *state {
+	s := statePool.Get().(*state)
+	indents := s.indents
+	if len(indents) > 1 && indents[1] != f.indent() {
+		indents = indents[:0]
+	}
+	*s = state{
 		Formatter: f,
-		w:         w,
-		seen:      map[any]bool{},
+		indent:    f.indent(),
+		maxDepth:  f.maxDepth(),
+		maxWidth:  f.maxWidth(),
+		maxBytes:  f.MaxBytes,
+		maxLines:  f.MaxLines,
+		compact:   f.Compact,
+		plain:     f.plain(),
+		seen:      s.seen,
+		path:      s.path[:0],
+		glued:     s.glued[:0],
+		scratch:   s.scratch[:0],
+		indents:   indents,
 		depth:     -1,
 	}
-	s.print(reflect.ValueOf(x))
+	if f.PullSeqs {
+		s.pulled = map[reflect.Value]pulledSeq{}
+	}
+	return s
+}
+
+// plain reports whether f has no settings for particular types,
+// so it prints all values of plainTypes by kind alone.
+func (f *Formatter) plain() bool {
+	return len(f.ignoreTypes) == 0 && len(f.redactTypes) == 0 && len(f.typeDepths) == 0 &&
+		len(f.transforms) == 0 && len(f.printers) == 0 && len(f.ifacePrinters) == 0
+}
+
+// free returns s to statePool, keeping only the allocations that
+// newState reuses. s must not be used afterward.
+func (s *state) free() {
+	clear(s.seen)
+	*s = state{seen: s.seen, path: s.path[:0], glued: s.glued[:0], scratch: s.scratch[:0], indents: s.indents}
+	statePool.Put(s)
+}
+
+// bufPool holds buffers for formatting values that are returned as strings.
+var bufPool = sync.Pool{
+	New: func() any { return new([]byte) },
+}
+
+// maxPooledBuf is the capacity of the largest buffer kept in bufPool,
+// so one large value doesn't keep memory in use.
+const maxPooledBuf = 64 << 10
+
+// sprintBuf returns the string that appendFn appends to a pooled buffer.
+func sprintBuf(appendFn func([]byte) []byte) string {
+	bp := bufPool.Get().(*[]byte)
+	b := appendFn((*bp)[:0])
+	str := string(b)
+	if cap(b) <= maxPooledBuf {
+		*bp = b
+		bufPool.Put(bp)
+	}
+	return str
+}
+
+// state holds the state of a single formatting operation.
+// The Formatter is never modified.
+type state struct {
+	*Formatter
+	indent   string          // resolved Formatter.Indent
+	maxDepth int             // resolved Formatter.MaxDepth
+	maxWidth int             // resolved Formatter.MaxWidth
+	maxBytes int             // Formatter.MaxBytes, or 0 after it is reached
+	maxLines int             // Formatter.MaxLines, or 0 after it is reached
+	compact  bool            // print on one line; starts as Formatter.Compact
+	plain    bool            // resolved Formatter.plain
+	buf      []byte          // output
+	discard  bool            // don't append to buf
+	theme    *Theme          // nil if not coloring
+	seen     map[ptrKey]bool // pointers being printed, to detect cycles
+	// With ShowSharing, the number of times each pointer is reached,
+	// and the labels assigned to pointers reached more than once.
+	// labels is nil while counting.
+	shared map[ptrKey]int
+	labels map[ptrKey]int
+	// With PointerLabels, the labels of the pointers printed so far.
+	pointerLabels map[ptrKey]int
+	path          Path // current path, if tracking paths
+	// The types whose transformers are being applied.
+	transforming map[reflect.Type]bool
+	depth        int
+	col          int
+	nbytes       int // bytes written, excluding escape sequences
+	lines        int // newlines written
+	// Tokens that must be written on the same line as the next one,
+	// like the "&" before a pointed-to value.
+	glued []token
+	// Space for formatting a token with prcAppend.
+	scratch []byte
+	// indents[n] is n levels of indentation; see indentation.
+	indents []string
+	// With PullSeqs, the values pulled from each iterator printed so far,
+	// so measuring doesn't call it again.
+	pulled map[reflect.Value]pulledSeq
+	// If non-nil, the reason formatting stopped early.
+	err error
+	// Stop with errNewline when writing a newline.
+	failOnNewline bool
+	// With Strict, the values not printed in full.
+	problems []*Problem
+	// The types whose MaxDepthFor limits are in effect.
+	depthTypes map[reflect.Type]bool
+	// With ChunkSize, where to write buf when it grows to that size.
+	out *chunkWriter
+}
+
+func (s *state) deeper(f func()) {
+	s.depth++
+	defer func() { s.depth-- }()
+	if s.depth > s.maxDepth {
+		s.problem(s.path[:max(len(s.path)-1, 0)], ErrMaxDepth)
+		s.prc(markerClass, s.markers().MaxDepth)
+		return
+	}
+	f()
+}
+
+func (s *state) print(v reflect.Value) {
 	if s.err != nil {
-		return s.err
+		return
 	}
-	if s.col != 0 && !f.Compact {
-		if _, err := io.WriteString(w, "\n"); err != nil {
-			return err
+	s.deeper(func() {
+		s.printSameDepth(v)
+	})
+}
+
+func (s *state) printSameDepth(v reflect.Value) {
+	if !v.IsValid() {
+		s.prc(keywordClass, "nil")
+		return
+	}
+
+	if s.plain && plainTypes[v.Type()] {
+		s.printKind(v)
+		return
+	}
+
+	if s.ignoreTypes[v.Type()] {
+		s.prType(v.Type())
+		s.prc(markerClass, "{...omitted}")
+		return
+	}
+
+	if s.redactTypes[v.Type()] {
+		s.prc(markerClass, s.markers().Redacted)
+		return
+	}
+
+	if n, ok := s.typeDepths[v.Type()]; ok && !s.depthTypes[v.Type()] {
+		if s.depthTypes == nil {
+			s.depthTypes = map[reflect.Type]bool{}
+		}
+		s.depthTypes[v.Type()] = true
+		defer delete(s.depthTypes, v.Type())
+		defer func(max int) { s.maxDepth = max }(s.maxDepth)
+		s.maxDepth = min(s.maxDepth, s.depth+n)
+	}
+
+	if fn := s.transformer(v, s.transforming); fn != nil {
+		var tv reflect.Value
+		if err := catch(func() { tv = fn(v) }); err != nil {
+			s.printPanic(err)
+			return
+		}
+		if s.transforming == nil {
+			s.transforming = map[reflect.Type]bool{}
+		}
+		s.transforming[v.Type()] = true
+		defer delete(s.transforming, v.Type())
+		s.printSameDepth(tv)
+		return
+	}
+
+	if s.ErrorTree && s.printers[v.Type()] == nil {
+		var ok bool
+		if err := catch(func() { ok = s.printError(v) }); err != nil {
+			s.printPanic(err)
+			return
+		}
+		if ok {
+			return
+		}
+	}
+
+	if str, ok, err := s.customString(v); err != nil {
+		s.printPanic(err)
+		return
+	} else if ok {
+		s.pr(str)
+		return
+	}
+
+	if s.printSync(v) || s.printContainer(v) || s.printOrderedMap(v) || s.printReflectValue(v) ||
+		s.printSQL(v) || s.printRawMessage(v) {
+		return
+	}
+
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.UnsafePointer {
+		p := pointerKey(v)
+		if s.shared != nil && v.Kind() == reflect.Pointer && !v.IsNil() && s.printShared(p) {
+			return
+		}
+		if !s.AssumeAcyclic {
+			if s.seen[p] {
+				s.prc(markerClass, s.markers().Cycle)
+				return
+			}
+			s.seen[p] = true
+			defer delete(s.seen, p)
+		}
+	}
+	s.printKind(v)
+}
+
+// plainTypes are the types common in decoded JSON. Values of these types
+// have no methods and aren't printed specially, so unless the Formatter
+// has settings for particular types, printSameDepth can skip to printKind.
+var plainTypes = map[reflect.Type]bool{
+	stringType:                 true,
+	reflect.TypeFor[int]():     true,
+	reflect.TypeFor[bool]():    true,
+	reflect.TypeFor[float64](): true,
+	reflect.TypeFor[[]byte]():  true,
+	reflect.TypeFor[[]any]():   true,
+	mapStringAnyType:           true,
+}
+
+var (
+	stringType       = reflect.TypeFor[string]()
+	mapStringAnyType = reflect.TypeFor[map[string]any]()
+)
+
+// printKind prints v according to its kind.
+func (s *state) printKind(v reflect.Value) {
+	if s.SoftDepth > 0 && s.depth > s.SoftDepth && s.printSummary(v) {
+		return
+	}
+
+	if s.Smart && !s.compact {
+		switch v.Kind() {
+		case reflect.Array, reflect.Slice, reflect.Map, reflect.Struct:
+			if s.fits(v) {
+				s.startLine()
+				s.compact = true
+				defer func() { s.compact = false }()
+			}
+		}
+	}
+
+	// Format scalars without fmt, so their methods aren't called.
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.prcAppend(numberClass, func(b []byte) []byte { return appendInt(b, v.Int(), s.intBase(v.Type())) })
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s.prcAppend(numberClass, func(b []byte) []byte { return appendUint(b, v.Uint(), s.intBase(v.Type())) })
+
+	case reflect.Float32, reflect.Float64:
+		s.prcAppend(numberClass, func(b []byte) []byte { return s.appendFloat(b, v.Float(), v.Type().Bits()) })
+
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		verb, prec := s.floatFormat()
+		c = complex(s.roundZero(real(c)), s.roundZero(imag(c)))
+		s.prc(numberClass, strconv.FormatComplex(c, verb, prec, v.Type().Bits()))
+
+	case reflect.Bool:
+		s.prc(keywordClass, strconv.FormatBool(v.Bool()))
+
+	case reflect.UnsafePointer:
+		if s.Deterministic && !v.IsNil() {
+			s.prc(numberClass, s.pointerLabel(pointerKey(v)))
+		} else {
+			s.prc(numberClass, formatPointer(v))
+		}
+
+	case reflect.String:
+		s.printString(v.String())
+
+	case reflect.Interface:
+		if s.GoSyntax {
+			s.printTyped(v.Elem())
+			break
+		}
+		if s.ShowDynamicTypes && !v.IsNil() {
+			s.printInterfaceType(v)
+		}
+		s.printSameDepth(v.Elem())
+
+	case reflect.Pointer:
+		if s.GoSyntax {
+			s.printGoPointer(v)
+			break
+		}
+		if s.ShowNil && v.IsNil() {
+			s.prTypedNil(v.Type())
+			break
+		}
+		s.glue(punctClass, "&")
+		if id := s.pointerID(v); id != "" {
+			s.glue(markerClass, id+" ")
+		}
+		s.printSameDepth(v.Elem())
+
+	case reflect.Array, reflect.Slice:
+		if s.BytesMode != BytesList && v.Type().Elem().Kind() == reflect.Uint8 &&
+			!((s.GoSyntax || s.ShowNil) && v.Kind() == reflect.Slice && v.IsNil()) {
+			s.printBytes(v, s.BytesMode)
+		} else {
+			s.printSlice(v)
+		}
+
+	case reflect.Map:
+		s.printMap(v)
+
+	case reflect.Struct:
+		s.printStruct(v)
+
+	case reflect.Chan:
+		s.printChan(v)
+
+	case reflect.Func:
+		s.printFunc(v)
+
+	default:
+		s.problem(s.path, ErrUnknownKind)
+		s.prc(markerClass, fmt.Sprintf("<unknown reflect kind:%s>", v.Kind()))
+	}
+}
+
+// fits reports whether v, printed compactly from the current position,
+// fits on the current line.
+func (s *state) fits(v reflect.Value) bool {
+	return s.measure(func(m *state) { m.printSameDepth(v) })
+}
+
+// measure reports whether the output of f, printed compactly
+// from the current position, fits on the current line.
+func (s *state) measure(f func(*state)) bool {
+	m := s.measurer()
+	if m == nil {
+		return false
+	}
+	if m.col == 0 {
+		m.col = m.advance(0, m.indentation(m.depth))
+	}
+	f(m)
+	return m.err == nil
+}
+
+// widthOf returns the number of columns that f prints compactly,
+// and reports whether it prints on one line.
+func (s *state) widthOf(f func(*state)) (int, bool) {
+	m := s.measurer()
+	if m == nil {
+		return 0, false
+	}
+	m.col = 0
+	m.maxWidth = 0
+	f(m)
+	return m.col, m.err == nil
+}
+
+// measurer returns a copy of s for measuring output without writing it,
+// or nil if s can't be measured.
+func (s *state) measurer() *state {
+	if s.shared != nil && s.labels == nil {
+		// Counting shared pointers; measuring would count them twice.
+		return nil
+	}
+	m := *s
+	m.discard = true
+	m.failOnNewline = true
+	m.glued = slices.Clone(s.glued)
+	m.theme = nil
+	m.compact = true
+	if m.labels != nil {
+		m.labels = maps.Clone(m.labels)
+	}
+	if m.pointerLabels != nil {
+		m.pointerLabels = maps.Clone(m.pointerLabels)
+	}
+	return &m
+}
+
+var (
+	errNewline = errors.New("newline")
+	errBudget  = errors.New("MaxBytes or MaxLines exceeded")
+)
+
+// A ptrKey identifies a pointer. The type distinguishes a pointer to
+// a struct from a pointer to its first field.
+type ptrKey struct {
+	t reflect.Type
+	p uintptr
+}
+
+// pointerKey returns the key for v, a pointer or unsafe.Pointer.
+// Unlike v.Interface(), it doesn't allocate.
+func pointerKey(v reflect.Value) ptrKey {
+	return ptrKey{v.Type(), v.Pointer()}
+}
+
+// pointerID returns the label of v, a pointer, with PointerLabels or
+// Deterministic, or its address with ShowAddresses. Otherwise it returns "".
+func (s *state) pointerID(v reflect.Value) string {
+	if v.IsNil() {
+		return ""
+	}
+	if s.PointerLabels || s.Deterministic {
+		return s.pointerLabel(pointerKey(v))
+	}
+	if s.ShowAddresses {
+		return formatPointer(v)
+	}
+	return ""
+}
+
+// pointerLabel returns the label of p, assigning the next one
+// if p hasn't been labeled.
+func (s *state) pointerLabel(p ptrKey) string {
+	n, ok := s.pointerLabels[p]
+	if !ok {
+		if s.pointerLabels == nil {
+			s.pointerLabels = map[ptrKey]int{}
+		}
+		n = len(s.pointerLabels) + 1
+		s.pointerLabels[p] = n
+	}
+	return "p#" + strconv.Itoa(n)
+}
+
+// showAddresses reports whether to show the addresses of
+// channels and functions.
+func (f *Formatter) showAddresses() bool {
+	return f.ShowAddresses && !f.Deterministic
+}
+
+// printChan prints a channel with its length and capacity, like
+// chan int(len=1, cap=10).
+func (s *state) printChan(v reflect.Value) {
+	var a string
+	if !v.IsNil() {
+		a = "len=" + strconv.Itoa(v.Len()) + ", cap=" + strconv.Itoa(v.Cap())
+		if s.showAddresses() {
+			a += ", " + formatPointer(v)
+		}
+	}
+	if s.GoSyntax {
+		s.prTypedNil(v.Type())
+		if a != "" {
+			s.prc(markerClass, " /* "+a+" */")
+		}
+		return
+	}
+	s.prType(v.Type())
+	if a == "" {
+		s.prc(punctClass, "(")
+		s.prc(keywordClass, "nil")
+		s.prc(punctClass, ")")
+	} else {
+		s.prc(markerClass, "("+a+")")
+	}
+}
+
+// printFunc prints a function by name, like func strings.ToUpper.
+// A closure, which has no useful name, is printed as its signature.
+func (s *state) printFunc(v reflect.Value) {
+	if s.PullSeqs && !s.GoSyntax && !v.IsNil() && s.printSeq(v) {
+		return
+	}
+	name := ""
+	if !v.IsNil() {
+		name = s.funcName(v)
+	}
+	if s.GoSyntax {
+		s.prTypedNil(v.Type())
+		c := name
+		if s.showAddresses() && !v.IsNil() {
+			c = strings.TrimSpace(c + " " + formatPointer(v))
+		}
+		if c != "" {
+			s.prc(markerClass, " /* "+c+" */")
+		}
+		return
+	}
+	switch {
+	case v.IsNil():
+		s.prTypedNil(v.Type())
+		return
+	case name != "":
+		s.prc(keywordClass, "func")
+		s.pr(" " + name)
+	default:
+		s.prType(v.Type())
+	}
+	if s.showAddresses() {
+		s.pr("(" + formatPointer(v) + ")")
+	}
+}
+
+// funcName returns the name of the function in v, a non-nil func, or ""
+// if it is a closure. With OmitPackage, the package is removed.
+func (f *Formatter) funcName(v reflect.Value) string {
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := strings.TrimSuffix(fn.Name(), "-fm") // method values
+	if closureName.MatchString(name) {
+		return ""
+	}
+	if f.OmitPackage {
+		// The package path may contain dots, but not after its last slash.
+		if i := strings.LastIndexByte(name, '/'); i >= 0 {
+			name = name[i+1:]
+		}
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			name = name[i+1:]
+		}
+	}
+	return name
+}
+
+// closureName matches the names the compiler gives to function literals,
+// like pkg.F.func1 and pkg.F.func2.1.
+var closureName = regexp.MustCompile(`\.func\d+(\.\d+)*$`)
+
+// formatPointer formats the address held by v, which must be
+// a pointer-like kind, like fmt's %v or %p.
+func formatPointer(v reflect.Value) string {
+	return "0x" + strconv.FormatUint(uint64(v.Pointer()), 16)
+}
+
+// printShared handles a pointer p when ShowSharing is set.
+// It reports whether p has been completely printed.
+func (s *state) printShared(p ptrKey) bool {
+	if s.labels == nil {
+		// Counting: don't expand a pointer more than once.
+		s.shared[p]++
+		return s.shared[p] > 1
+	}
+	if s.shared[p] <= 1 {
+		return false
+	}
+	if n, ok := s.labels[p]; ok {
+		s.prc(markerClass, fmt.Sprintf("#%d", n))
+		return true
+	}
+	n := len(s.labels) + 1
+	s.labels[p] = n
+	s.prc(markerClass, fmt.Sprintf("#%d=", n))
+	return false
+}
+
+// printString prints str as a quoted string, observing MaxStringLen.
+func (s *state) printString(str string) {
+	if s.escapeString(str) {
+		return
+	}
+	n := len(str)
+	if s.MaxStringLen > 0 && n > s.MaxStringLen {
+		// Don't split a rune.
+		n = s.MaxStringLen
+		for n > 0 && !utf8.RuneStart(str[n]) {
+			n--
+		}
+	}
+	out := str[:n]
+	if s.StringEscape == EscapeReplace {
+		out = replaceUnprintable(out)
+	}
+	if !(strings.Contains(out, "\n") && s.printMultiline(out)) {
+		s.prcAppend(stringClass, func(b []byte) []byte { return strconv.AppendQuote(b, out) })
+	}
+	if n < len(str) {
+		s.prc(markerClass, fmt.Sprintf("%s(+%d bytes)", s.markers().Truncated, len(str)-n))
+	}
+}
+
+// floatFormat returns the format and precision for strconv.FormatFloat.
+func (f *Formatter) floatFormat() (byte, int) {
+	verb, prec := f.FloatFormat, f.FloatPrecision
+	if verb == 0 {
+		verb = 'g'
+	}
+	if prec <= 0 {
+		prec = -1 // shortest that round-trips
+	}
+	return verb, prec
+}
+
+// roundZero returns 0 for x within FloatTolerance of 0.
+func (f *Formatter) roundZero(x float64) float64 {
+	if math.Abs(x) < f.FloatTolerance {
+		return 0
+	}
+	return x
+}
+
+// appendFloat appends x, a float of the given size, to dst.
+// In GoSyntax, NaNs and infinities are expressions.
+func (s *state) appendFloat(dst []byte, x float64, bits int) []byte {
+	if s.GoSyntax {
+		switch {
+		case math.IsNaN(x):
+			return append(dst, "math.NaN()"...)
+		case math.IsInf(x, 1):
+			return append(dst, "math.Inf(1)"...)
+		case math.IsInf(x, -1):
+			return append(dst, "math.Inf(-1)"...)
+		}
+	}
+	verb, prec := s.floatFormat()
+	return strconv.AppendFloat(dst, s.roundZero(x), verb, prec, bits)
+}
+
+// intBase returns the base for printing integers of type t.
+func (s *state) intBase(t reflect.Type) int {
+	if s.AutoHex && (strings.Contains(t.Name(), "Flags") || strings.Contains(t.Name(), "Mask")) {
+		return 16
+	}
+	return s.IntBase
+}
+
+// printInterfaceType prints the static and dynamic types of v,
+// a non-nil interface, as "iface(dynamic) ".
+func (s *state) printInterfaceType(v reflect.Value) {
+	iface := "any"
+	if v.Type() != reflect.TypeFor[any]() {
+		iface = s.typeName(v.Type())
+	}
+	s.glue(typeClass, iface)
+	s.glue(punctClass, "(")
+	s.glue(typeClass, s.typeName(v.Elem().Type()))
+	s.glue(punctClass, ")")
+	s.glue(plain, " ")
+}
+
+// printTyped prints v so that the result has v's type in Go syntax,
+// even where the type can't be inferred from the context, as
+// with the elements of an []any.
+func (s *state) printTyped(v reflect.Value) {
+	if v.IsValid() && needsConversion(v.Type()) {
+		s.prType(v.Type())
+		s.prc(punctClass, "(")
+		s.printSameDepth(v)
+		s.prc(punctClass, ")")
+	} else {
+		s.printSameDepth(v)
+	}
+}
+
+// needsConversion reports whether values of type t
+// must be explicitly converted to t when printed as Go expressions.
+// Basic types whose literals are untyped constants need the conversion,
+// except for the default types of those constants.
+func needsConversion(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.String, reflect.Bool:
+		return t.PkgPath() != ""
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128:
+		return true
+	default:
+		return false
+	}
+}
+
+// printGoPointer prints a pointer in Go syntax.
+func (s *state) printGoPointer(v reflect.Value) {
+	if v.IsNil() {
+		s.prTypedNil(v.Type())
+		return
+	}
+	if id := s.pointerID(v); id != "" {
+		s.glue(markerClass, "/* "+id+" */ ")
+	}
+	switch v.Elem().Kind() {
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
+		// The address of a composite literal.
+		s.prc(punctClass, "&")
+		s.printSameDepth(v.Elem())
+	default:
+		// A function literal returning the address of a variable,
+		// since new(expr) requires Go 1.26.
+		s.prc(keywordClass, "func")
+		s.prc(punctClass, "() ")
+		s.prType(v.Type())
+		s.prc(punctClass, " { ")
+		s.pr("v := ")
+		s.printTyped(v.Elem())
+		s.prc(punctClass, "; ")
+		s.prc(keywordClass, "return")
+		s.pr(" &v ")
+		s.prc(punctClass, "}()")
+	}
+}
+
+// prTypedNil prints a nil value of type t in Go syntax.
+func (s *state) prTypedNil(t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Func, reflect.Chan:
+		s.prc(punctClass, "(")
+		s.prType(t)
+		s.prc(punctClass, ")")
+	default:
+		s.prType(t)
+	}
+	s.prc(punctClass, "(")
+	s.prc(keywordClass, "nil")
+	s.prc(punctClass, ")")
+}
+
+// printSummary prints v, if it is a non-empty struct, array, slice or
+// map, as its type and size, like Config{...7 fields}, for SoftDepth.
+// It reports whether it did.
+func (s *state) printSummary(v reflect.Value) bool {
+	var n int
+	var one, many string
+	switch v.Kind() {
+	case reflect.Struct:
+		n, one, many = exportedFields(v.Type()), "field", "fields"
+	case reflect.Array, reflect.Slice:
+		if s.BytesMode != BytesList && v.Type().Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+		n, one, many = v.Len(), "element", "elements"
+	case reflect.Map:
+		n, one, many = v.Len(), "entry", "entries"
+	default:
+		return false
+	}
+	if n == 0 {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		s.prType(v.Type())
+	case reflect.Map:
+		if s.GoSyntax || v.Type().Name() != "" {
+			s.prType(v.Type())
+		}
+	default:
+		s.printSliceType(v)
+	}
+	what := many
+	if n == 1 {
+		what = one
+	}
+	s.prc(punctClass, "{")
+	s.prc(markerClass, s.markers().Truncated+strconv.Itoa(n)+" "+what)
+	s.prc(punctClass, "}")
+	return true
+}
+
+// print slice or array
+func (s *state) printSlice(v reflect.Value) {
+	if v.Kind() == reflect.Slice && v.IsNil() && s.printNil(v) {
+		return
+	}
+	index := v.Index
+	if elems, err := s.sortedElements(v); err != nil {
+		s.printPanic(err)
+		return
+	} else if elems != nil {
+		index = func(i int) reflect.Value { return elems[i] }
+	}
+	s.printSliceType(v)
+	s.openBrace(v)
+	if s.parallel(v) {
+		s.printParallel(v.Len(), index)
+		return
+	}
+	s.printElements(v.Len(), s.maxElements(v.Type()), s.TailElements, index)
+}
+
+// printElements prints the elements of a slice or other sequence,
+// after the opening brace. index returns each of the count elements.
+// At most limit elements are printed, if it is positive.
+// If tail is also positive, the last tail elements are printed after
+// the first limit; see [Formatter.TailElements].
+func (s *state) printElements(count, limit, tail int, index func(int) reflect.Value) {
+	if !s.compact {
+		s.pr("\n")
+	}
+	n := 0 // number of elements printed
+	for i := 0; i < count; i++ {
+		if s.err != nil {
+			return
+		}
+		if skip := tailSkip(i, count, limit, tail); skip > 0 {
+			s.printTruncated(n, skip)
+			i += skip - 1
+			continue
+		}
+		if !s.enterIndex(i) {
+			continue
+		}
+		if limit > 0 && n >= limit && tail <= 0 {
+			s.leave()
+			s.printTruncated(n, 0)
+			break
+		}
+		elem := func(s *state) { s.print(index(i)) }
+		s.beforeElement(n, elem)
+		line := s.lines
+		elem(s)
+		s.afterElement(line)
+		s.leave()
+		n++
+	}
+	s.prc(punctClass, "}")
+}
+
+// beforeElement and afterElement separate the elements
+// of a slice, array or map. n is the number of elements already printed.
+// elem prints the next element, for measuring.
+func (s *state) beforeElement(n int, elem func(*state)) {
+	if s.compact && n > 0 {
+		s.separate(elem)
+	}
+}
+
+// separate writes the separator before an element in compact mode.
+// If MaxWidth is set and the element printed by elem won't fit on the
+// current line, it starts a new line, indented one level deeper than
+// the current value.
+func (s *state) separate(elem func(*state)) {
+	s.writeClass(punctClass, ",")
+	if s.maxWidth > 0 && !s.failOnNewline &&
+		!s.measure(func(m *state) { m.write(" "); elem(m) }) {
+		s.write("\n")
+		s.writeIndent(s.depth + 1)
+		return
+	}
+	s.write(" ")
+}
+
+// line is the number of lines written before the element.
+func (s *state) afterElement(line int) {
+	if s.compact {
+		return
+	}
+	if s.PathComments && s.lines == line {
+		s.prc(punctClass, ",")
+		s.pathComment()
+		s.pr("\n")
+		return
+	}
+	s.after(",")
+}
+
+// pathComment writes the current path as a comment, for PathComments.
+// It ignores MaxWidth.
+func (s *state) pathComment() {
+	s.writeClass(plain, "  // "+s.path.String())
+}
+
+// tailSkip returns the number of elements to omit before the i'th of
+// count elements, if the last tail of them follow the first limit.
+func tailSkip(i, count, limit, tail int) int {
+	if tail <= 0 || limit <= 0 || i != limit || count <= limit+tail {
+		return 0
+	}
+	return count - limit - tail
+}
+
+// printTruncated marks the elements omitted because of MaxElements.
+// n is the number of elements already printed.
+// If omitted is positive, the marker includes it, as in ...(+5).
+func (s *state) printTruncated(n, omitted int) {
+	str := s.markers().Truncated
+	if omitted > 0 {
+		str = fmt.Sprintf("%s(+%d)", str, omitted)
+	}
+	if s.compact {
+		marker := func(s *state) { s.prc(markerClass, str) }
+		s.beforeElement(n, marker)
+		marker(s)
+	} else {
+		s.depth++
+		s.prc(markerClass, str)
+		s.pr("\n")
+		s.depth--
+	}
+}
+
+// openBrace prints the brace that begins the elements of a slice, array or map,
+// preceded by its length if ShowLen is set.
+func (s *state) openBrace(v reflect.Value) {
+	if s.ShowLen {
+		s.printLen(v)
+	}
+	s.prc(punctClass, "{")
+}
+
+// printLen prints the length and capacity of a slice, or the length of a map.
+// Arrays are skipped, since their length is part of their type.
+func (s *state) printLen(v reflect.Value) {
+	var a string
+	switch v.Kind() {
+	case reflect.Slice:
+		a = "len=" + strconv.Itoa(v.Len()) + ", cap=" + strconv.Itoa(v.Cap())
+	case reflect.Map:
+		a = "len=" + strconv.Itoa(v.Len())
+	default:
+		return
+	}
+	if s.GoSyntax {
+		s.prc(markerClass, "/*"+a+"*/")
+	} else {
+		s.prc(markerClass, "("+a+")")
+	}
+}
+
+// printNil prints a nil slice or map, if GoSyntax or ShowNil calls for
+// distinguishing it from an empty one. It reports whether it printed anything.
+func (s *state) printNil(v reflect.Value) bool {
+	switch {
+	case s.GoSyntax:
+		s.prTypedNil(v.Type())
+	case s.ShowNil:
+		s.prc(keywordClass, "nil")
+	default:
+		return false
+	}
+	return true
+}
+
+// printSliceType prints the type that begins a slice or array literal.
+// Defined types print their name.
+func (s *state) printSliceType(v reflect.Value) {
+	if s.GoSyntax || v.Type().Name() != "" {
+		s.prType(v.Type())
+	} else if v.Kind() == reflect.Array {
+		s.prc(typeClass, fmt.Sprintf("[%d]", v.Len()))
+	} else {
+		s.prc(typeClass, "[]")
+	}
+}
+
+func (s *state) printMap(v reflect.Value) {
+	if v.IsNil() && s.printNil(v) {
+		return
+	}
+	es, err := mapEntries(v)
+	if err == nil {
+		es, err = s.orderEntries(es)
+	}
+	if err != nil {
+		s.printPanic(err)
+		return
+	}
+	set := s.isSet(v, es)
+	if s.GoSyntax || v.Type().Name() != "" {
+		s.prType(v.Type())
+	} else if set {
+		s.prc(keywordClass, "set")
+	}
+	if set {
+		for i := range es {
+			es[i].val = reflect.Value{}
+		}
+	}
+	s.openBrace(v)
+	s.printEntries(es, s.maxElements(v.Type()), s.TailElements)
+}
+
+var emptyStructType = reflect.TypeFor[struct{}]()
+
+// isSet reports whether to print the map v, whose entries are es, as a
+// set of its keys: its values are all true, or all struct{}{}.
+func (s *state) isSet(v reflect.Value, es []mapEntry) bool {
+	if s.RawSets || s.GoSyntax || len(es) == 0 {
+		return false
+	}
+	switch v.Type().Elem() {
+	case emptyStructType:
+		return true
+	case boolType:
+		for _, e := range es {
+			if !e.val.Bool() {
+				return false
+			}
 		}
+		return true
 	}
-	return nil
+	return false
 }
 
-type state struct {
-	*Formatter
-	w     io.Writer
-	seen  map[any]bool
-	depth int
-	col   int
-	err   error
+// printEntries prints the entries of a map, after the opening brace,
+// observing limit and tail as printElements does.
+func (s *state) printEntries(es []mapEntry, limit, tail int) {
+	if !s.compact {
+		s.pr("\n")
+	}
+	width := s.keyWidth(es)
+	n := 0 // number of entries printed
+	for i := 0; i < len(es); i++ {
+		if s.err != nil {
+			return
+		}
+		if skip := tailSkip(i, len(es), limit, tail); skip > 0 {
+			s.printTruncated(n, skip)
+			i += skip - 1
+			continue
+		}
+		e := es[i]
+		if !s.enterKey(e.key) {
+			continue
+		}
+		if limit > 0 && n >= limit && tail <= 0 {
+			s.leave()
+			s.printTruncated(n, 0)
+			break
+		}
+		entry := func(s *state) {
+			s.print(e.key)
+			if !e.val.IsValid() {
+				return
+			}
+			w := 0
+			if width > 0 {
+				w, _ = s.widthOf(func(m *state) { m.print(e.key) })
+			}
+			s.align(w, width)
+			s.print(e.val)
+		}
+		s.beforeElement(n, entry)
+		line := s.lines
+		entry(s)
+		s.afterElement(line)
+		s.leave()
+		n++
+	}
+	s.prc(punctClass, "}")
 }
 
-func (s *state) deeper(f func()) {
-	s.depth++
-	defer func() { s.depth-- }()
-	if s.depth > s.MaxDepth {
-		s.pr("<maxdepth>")
-		return
+// keyWidth returns the width of the widest key in es, to align their
+// values with AlignFields. It returns 0 if the values should not be
+// aligned, because AlignFields is unset or a key doesn't fit on a line.
+func (s *state) keyWidth(es []mapEntry) int {
+	if !s.AlignFields || s.compact || (len(es) > 0 && !es[0].val.IsValid()) {
+		return 0
 	}
-	f()
+	width := 0
+	for _, e := range es {
+		w, ok := s.widthOf(func(m *state) { m.print(e.key) })
+		if !ok {
+			return 0
+		}
+		width = max(width, w)
+	}
+	return width
 }
 
-func (s *state) print(v reflect.Value) {
-	s.deeper(func() {
-		s.printSameDepth(v)
-	})
+// A mapEntry is a key and value from a map.
+// The entries of a set, printed with only their keys, have no value.
+type mapEntry struct {
+	key, val reflect.Value
 }
 
-func (s *state) printSameDepth(v reflect.Value) {
-	if !v.IsValid() {
-		s.pr("nil")
-		return
+// mapEntries returns the entries of the map v, sorted by key.
+// Because it iterates over the map instead of looking up keys,
+// it includes entries whose keys are not equal to themselves, like NaNs.
+// Entries with equal keys are ordered by value.
+// If a Compare or Less method of the keys panics, it returns the panic
+// as err, and the entries are in no particular order.
+func mapEntries(v reflect.Value) (_ []mapEntry, err error) {
+	es := make([]mapEntry, 0, v.Len())
+	if v.Type() == mapStringAnyType && v.CanInterface() {
+		// Copy the entries into a slice, rather than allocating
+		// each key and value separately as MapRange does.
+		m := v.Interface().(map[string]any)
+		kvs := make([]stringAnyEntry, 0, len(m))
+		for k, v := range m {
+			kvs = append(kvs, stringAnyEntry{k, v})
+		}
+		slices.SortFunc(kvs, func(a, b stringAnyEntry) int {
+			return strings.Compare(a.Key, b.Key)
+		})
+		sv := reflect.ValueOf(kvs)
+		for i := range kvs {
+			e := sv.Index(i)
+			es = append(es, mapEntry{e.Field(0), e.Field(1)})
+		}
+		return es, nil
 	}
+	iter := v.MapRange()
+	for iter.Next() {
+		es = append(es, mapEntry{iter.Key(), iter.Value()})
+	}
+	if v.Type().Key() == stringType {
+		// Keys are distinct, and strings have no Compare method.
+		slices.SortFunc(es, func(e1, e2 mapEntry) int {
+			return strings.Compare(e1.key.String(), e2.key.String())
+		})
+	} else {
+		err = sortEntries(es)
+	}
+	return es, err
+}
 
-	value := v.Interface()
+// orderEntries sorts es, which are sorted by key, by MapKeyOrder if it is set.
+// It returns es. If MapKeyOrder panics, it returns the panic as err,
+// and es is in no particular order.
+func (f *Formatter) orderEntries(es []mapEntry) (_ []mapEntry, err error) {
+	if f.MapKeyOrder != nil {
+		err = catch(func() {
+			slices.SortStableFunc(es, func(e1, e2 mapEntry) int { return f.MapKeyOrder(e1.key, e2.key) })
+		})
+	}
+	return es, err
+}
 
-	if v.Kind() == reflect.Pointer || v.Kind() == reflect.UnsafePointer {
-		if s.seen[value] {
-			s.prf("<cycle>")
-			return
-		} else {
-			s.seen[value] = true
-			defer func() { delete(s.seen, value) }()
+// compareKeys compares map keys in the order of map entries.
+func (f *Formatter) compareKeys(k1, k2 reflect.Value) int {
+	if f.MapKeyOrder != nil {
+		if c := f.MapKeyOrder(k1, k2); c != 0 {
+			return c
 		}
 	}
+	return compareValues(k1, k2)
+}
 
-	switch v.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
-		reflect.Uintptr, reflect.UnsafePointer,
-		reflect.Float32, reflect.Float64,
-		reflect.Complex64, reflect.Complex128,
-		reflect.Bool:
-		s.prf("%v", value)
-
-	case reflect.String:
-		s.prf("%q", v)
-
-	case reflect.Interface:
-		s.printSameDepth(v.Elem())
-
-	case reflect.Pointer:
-		s.pr("&")
-		// TODO: no linebreak between & and the rest.
-		s.printSameDepth(v.Elem())
-
-	case reflect.Array, reflect.Slice:
-		s.printSlice(v)
-
-	case reflect.Map:
-		s.printMap(v)
-
-	case reflect.Struct:
-		s.printStruct(v)
-
-	case reflect.Func, reflect.Chan:
-		s.prf("%s(%[1]v)", s.typeName(v.Type()))
+// A stringAnyEntry is an entry of a map[string]any.
+// Its fields are exported so that their reflect.Values can be interfaced.
+type stringAnyEntry struct {
+	Key string
+	Val any
+}
 
-	default:
-		s.prf("<unknown reflect kind:%s>", v.Kind())
-	}
+// sortEntries sorts es by key, and entries with equal keys by value.
+// If a Compare or Less method panics, it returns the panic.
+func sortEntries(es []mapEntry) error {
+	return catch(func() {
+		slices.SortFunc(es, func(e1, e2 mapEntry) int {
+			return cmp.Or(compareValues(e1.key, e2.key), compareValues(e1.val, e2.val))
+		})
+	})
 }
 
-// print slice or array
-func (s *state) printSlice(v reflect.Value) {
-	if v.Kind() == reflect.Array {
-		s.prf("[%d]{", v.Len())
-	} else {
-		s.pr("[]{")
-	}
-	if !s.Compact {
+func (s *state) printStruct(v reflect.Value) {
+	s.prType(v.Type())
+	s.prc(punctClass, "{")
+	if !s.compact {
 		s.pr("\n")
 	}
-	for i := range v.Len() {
-		if s.MaxElements > 0 && i >= s.MaxElements {
-			if s.Compact {
-				s.pr("...")
+	var fl fieldList
+	s.collectFields(&fl, v, nil, nil)
+	defer func() {
+		for _, p := range fl.ptrs {
+			delete(s.seen, p)
+		}
+	}()
+	// With AlignFields, pad the names so the values line up.
+	width := 0
+	if s.AlignFields && !s.compact {
+		for _, pf := range fl.fields {
+			width = max(width, s.width(pf.name))
+		}
+	}
+	for i, pf := range fl.fields {
+		if s.err != nil {
+			return
+		}
+		for _, step := range pf.steps {
+			s.enterField(step)
+		}
+		printField := func(s *state) {
+			s.deeper(func() { s.prc(fieldClass, pf.name) })
+			s.align(s.width(pf.name), width)
+			if pf.err != nil {
+				s.printPanic(pf.err)
 			} else {
-				s.depth++
-				s.pr("...\n")
-				s.depth--
+				s.printField(pf.val, pf.tag)
 			}
-			break
 		}
-		s.print(v.Index(i))
-		if !s.Compact || i != v.Len()-1 {
-			s.after(",")
+		if i > 0 && s.compact {
+			s.separate(printField)
+		}
+		line := s.lines
+		printField(s)
+		if !s.compact {
+			if s.GoSyntax || s.TrailingCommas {
+				s.prc(punctClass, ",")
+			}
+			if s.PathComments && s.lines == line {
+				s.pathComment()
+			}
+			s.pr("\n")
+		}
+		for range pf.steps {
+			s.leave()
 		}
 	}
-	s.pr("}")
-}
-
-func (s *state) printMap(v reflect.Value) {
-	keys := v.MapKeys()
-	slices.SortFunc(keys, compareValues)
-	// TODO: use mapiter for NaNs?
-	s.pr("{")
-	if !s.Compact {
-		s.pr("\n")
+	if s.err != nil {
+		return
 	}
-	for i, key := range keys {
-		if s.MaxElements > 0 && i >= s.MaxElements {
-			s.pr("...")
-			break
-		}
-		val := v.MapIndex(key)
-		s.print(key)
-		s.between(":")
-		s.print(val)
-		if !s.Compact || i != len(keys)-1 {
-			s.after(",")
+	if fl.unexported && s.GoSyntax {
+		const msg = "unexported fields omitted"
+		if !s.compact {
+			s.deeper(func() { s.pr("// " + msg + "\n") })
+		} else if len(fl.fields) == 0 {
+			s.pr("/* " + msg + " */")
+		} else {
+			s.pr(" /* " + msg + " */")
 		}
 	}
-	s.pr("}")
+	s.prc(punctClass, "}")
 }
 
-func (s *state) printStruct(v reflect.Value) {
+// A fieldList holds the struct fields to print.
+type fieldList struct {
+	fields     []printedField
+	unexported bool     // whether any unexported fields would have been printed
+	ptrs       []ptrKey // pointers to flattened structs, marked as seen
+}
+
+// A printedField is a struct field to print.
+type printedField struct {
+	steps []string // path steps to the field; more than one if flattened
+	name  string   // the name to print
+	val   reflect.Value
+	tag   tagOptions
+	err   error // a panic from a method or function called on val, printed instead of it
+}
+
+// collectFields adds the fields of v, a struct, to fl, other than those
+// named in shadowed. steps is the path to v from the struct being printed.
+// With Flatten, it adds the fields of an embedded struct in place of
+// the struct itself.
+func (s *state) collectFields(fl *fieldList, v reflect.Value, steps, shadowed []string) {
 	t := v.Type()
 	ignore := s.ignoreFields[t]
-	s.prf("%s{", s.typeName(t))
-	if !s.Compact {
-		s.pr("\n")
-	}
-	first := true
-	for i := range t.NumField() {
-		sf := t.Field(i)
-		if slices.Contains(ignore, sf.Name) {
+	redact := s.redactFields[t]
+	for _, fp := range s.fieldPlans(t) {
+		sf := fp.field
+		if s.ignoredField(ignore, sf.Name) || slices.Contains(shadowed, sf.Name) {
 			continue
 		}
-		if len(sf.Index) != 1 {
-			panic("len(index) != 1")
+		val := v.Field(fp.index)
+		elide, err := s.elideZero(t, fp, val)
+		if elide {
+			continue
 		}
-
-		if !sf.IsExported() {
+		// As with encoding/json, the exported fields of an embedded
+		// struct are promoted even if the struct's type is unexported.
+		if inner, ok := s.flattened(fp, val); ok {
+			if val.Kind() == reflect.Pointer {
+				p := pointerKey(val)
+				s.seen[p] = true
+				fl.ptrs = append(fl.ptrs, p)
+			}
+			if s.enterField(sf.Name) {
+				s.collectFields(fl, inner, append(slices.Clip(steps), sf.Name), slices.Concat(shadowed, fieldNames(t)))
+				s.leave()
+			}
 			continue
 		}
-		val := v.Field(i)
-		if !s.ShowZero && val.IsZero() {
+		if !fp.exported {
+			fl.unexported = true
 			continue
 		}
-		if !first && s.Compact {
-			s.pr(", ")
+		name, ok := s.fieldName(fp)
+		if !ok || !s.enterField(sf.Name) {
+			continue
 		}
-		s.deeper(func() { s.pr(sf.Name) })
-		s.between(":")
-		s.print(val)
-		first = false
-		if !s.Compact {
-			s.pr("\n")
+		s.leave()
+		if err == nil {
+			var keep bool
+			if keep, err = s.keepField(sf, val); !keep {
+				continue
+			}
 		}
+		tag := fp.tag
+		if slices.Contains(redact, sf.Name) {
+			tag.redact = true
+		}
+		fl.fields = append(fl.fields, printedField{append(slices.Clip(steps), sf.Name), name, val, tag, err})
 	}
-	s.pr("}")
 }
 
-func (s *state) typeName(t reflect.Type) string {
-	n := t.String()
-	if !s.OmitPackage {
-		return n
+// flattened returns the struct whose fields should be printed in place
+// of the field described by fp, whose value is v, and reports whether
+// there is one. With Flatten, that is the struct in an embedded field of
+// struct type, or pointed to by an embedded field of pointer type.
+// Pointers that would make a cycle are not flattened.
+func (s *state) flattened(fp fieldPlan, v reflect.Value) (reflect.Value, bool) {
+	if !s.Flatten || s.GoSyntax || !fp.field.Anonymous || fp.tag.special() {
+		return reflect.Value{}, false
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() || s.seen[pointerKey(v)] {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || s.printedSpecially(v.Type()) {
+		return reflect.Value{}, false
+	}
+	if _, ok, _ := s.customString(v); ok {
+		return reflect.Value{}, false
+	}
+	return v, true
+}
+
+// fieldNames returns the names of the fields of struct type t.
+func fieldNames(t reflect.Type) []string {
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i] = t.Field(i).Name
+	}
+	return names
+}
+
+type isZeroer interface{ IsZero() bool }
+
+var isZeroerType = reflect.TypeFor[isZeroer]()
+
+// isZero reports whether v is the zero value of its type or, with
+// UseIsZero, whether v has an IsZero method that returns true.
+// If the IsZero method panics, it returns the panic as err.
+func (f *Formatter) isZero(v reflect.Value) (zero bool, err error) {
+	if v.IsZero() {
+		return true, nil
+	}
+	if !f.UseIsZero || !v.CanInterface() {
+		return false, nil
+	}
+	z, ok := v.Interface().(isZeroer)
+	if !ok && reflect.PointerTo(v.Type()).Implements(isZeroerType) {
+		// The method has a pointer receiver. Use it even if v isn't
+		// addressable, so that x and &x print the same.
+		z, ok = addressOf(v).Interface().(isZeroer)
 	}
-	if i := strings.LastIndex(n, "."); i > 0 {
-		return n[i+1:]
+	if ok {
+		err = catch(func() { zero = z.IsZero() })
 	}
-	return n
+	return zero, err
+}
+
+// fieldName returns the name to print for an exported field, and
+// reports whether to print the field at all.
+func (f *Formatter) fieldName(fp fieldPlan) (string, bool) {
+	switch {
+	case f.GoSyntax:
+		return fp.field.Name, true
+	case f.FieldNameFunc != nil:
+		name := f.FieldNameFunc(fp.field)
+		return name, name != ""
+	case f.JSONNames:
+		return fp.json.name, !fp.json.omit
+	default:
+		return fp.field.Name, true
+	}
+}
+
+// elideZero reports whether the field of struct type t described by fp
+// should be omitted because its value, v, is zero.
+// If an IsZero method panics, it returns the panic as err.
+func (f *Formatter) elideZero(t reflect.Type, fp fieldPlan, v reflect.Value) (bool, error) {
+	if zero, err := f.isZero(v); !zero {
+		return false, err
+	}
+	if fp.tag.omitZero || (f.JSONNames && !f.GoSyntax && fp.json.omitEmpty) {
+		return true, nil
+	}
+	return !f.ShowZero && !f.zeroTypes[fp.field.Type] && !slices.Contains(f.zeroFields[t], fp.field.Name), nil
 }
 
 func (s *state) after(str string) {
-	s.write(str)
-	s.checkWidth("")
+	s.writeClass(punctClass, str)
+	s.checkWidth(0)
 	if s.col != 0 {
-		if s.Compact {
+		if s.compact {
 			s.write(" ")
 		} else {
 			s.write("\n")
@@ -300,56 +2227,281 @@ func (s *state) after(str string) {
 	}
 }
 
+// align writes the ":" after a field name or map key that occupies w
+// columns, and the padding that lines up the values after names of up
+// to width columns. With TabAlign, it pads with tabs.
+func (s *state) align(w, width int) {
+	if !s.TabAlign || width == 0 {
+		s.between(":")
+		if w < width {
+			s.write(strings.Repeat(" ", width-w))
+		}
+		return
+	}
+	s.writeClass(punctClass, ":")
+	// The first tab stop after the widest name and its colon.
+	stop := s.advance(s.col-w+width, "\t")
+	for s.col < stop {
+		s.write("\t")
+	}
+}
+
 func (s *state) between(str string) {
-	s.write(str)
-	s.checkWidth("")
+	s.writeClass(punctClass, str)
+	s.checkWidth(0)
 	if s.col != 0 {
 		s.write(" ")
 	}
 }
 
-// Observe MaxWidth.
-func (s *state) checkWidth(str string) {
-	if s.MaxWidth > 0 && s.col+len(str) >= s.MaxWidth {
+// Observe MaxWidth, breaking the line if n more columns won't fit.
+// In compact mode, lines break only between elements, in separate,
+// but measuring still fails when a line is too long.
+func (s *state) checkWidth(n int) {
+	if s.compact && !s.failOnNewline {
+		return
+	}
+	if s.maxWidth > 0 && s.col+n >= s.maxWidth {
 		s.write("\n")
 	}
 }
 
-func (s *state) prf(format string, args ...any) {
-	s.pr(fmt.Sprintf(format, args...))
+func (s *state) pr(str string) {
+	s.prc(plain, str)
 }
 
-func (s *state) pr(str string) {
+// prc is like pr, but colors str according to c.
+// Any glued tokens are written first, on the same line.
+func (s *state) prc(c class, str string) {
+	s.prcToken(c, str, !strings.ContainsAny(str, "\n\t"))
+}
+
+// prcAppend is like prc, for a token that fn appends to a buffer,
+// like a number or a quoted string, which must not contain newlines or tabs.
+// It formats the token without allocating a string for it.
+func (s *state) prcAppend(c class, fn func([]byte) []byte) {
 	if s.err != nil {
 		return
 	}
-	s.checkWidth(str)
+	s.scratch = fn(s.scratch[:0])
+	// prcToken doesn't retain the string.
+	s.prcToken(c, unsafe.String(unsafe.SliceData(s.scratch), len(s.scratch)), true)
+}
 
-	// Observe indent.
-	if !s.Compact && s.col == 0 {
-		for range s.depth {
-			s.write(s.Indent)
-		}
+// prcToken implements prc. If simple is true, str contains no newlines or
+// tabs, so its width is the number of columns it advances.
+func (s *state) prcToken(c class, str string, simple bool) {
+	if s.err != nil {
+		return
+	}
+	w := s.width(str)
+	n := w
+	for _, t := range s.glued {
+		n += s.width(t.str)
+	}
+	s.checkWidth(n)
+
+	s.startLine()
+	s.writeGlued()
+	if !simple {
+		w = -1
+	}
+	s.writeClassWidth(c, str, w)
+}
+
+// A token is a string to write and its class.
+type token struct {
+	class class
+	str   string
+}
+
+// glue arranges for str to be written with the next token printed by prc,
+// so that no line break separates them.
+func (s *state) glue(c class, str string) {
+	s.glued = append(s.glued, token{c, str})
+}
+
+// writeGlued writes the tokens glued to the next one.
+func (s *state) writeGlued() {
+	for _, t := range s.glued {
+		s.writeClass(t.class, t.str)
+	}
+	s.glued = s.glued[:0]
+}
+
+// startLine writes the indentation, if at the start of a line.
+func (s *state) startLine() {
+	if !s.compact && s.col == 0 {
+		s.writeIndent(s.depth)
+	}
+}
+
+// writeIndent writes n levels of indentation.
+// With IndentGuides, each level begins with a vertical line.
+func (s *state) writeIndent(n int) {
+	if !s.IndentGuides {
+		s.write(s.indentation(n))
+		return
+	}
+	for range n {
+		s.writeClass(punctClass, "│")
+		s.write(s.indent[min(1, len(s.indent)):])
+	}
+}
+
+// indentation returns n levels of indentation, without IndentGuides.
+// It caches the result, so deeply nested lines are indented with a
+// single write.
+func (s *state) indentation(n int) string {
+	for len(s.indents) <= n {
+		s.indents = append(s.indents, strings.Repeat(s.indent, len(s.indents)))
+	}
+	return s.indents[n]
+}
+
+// writeClass writes str, surrounded by the theme's escape sequences for c.
+func (s *state) writeClass(c class, str string) {
+	s.writeClassWidth(c, str, -1)
+}
+
+// writeClassWidth is like writeClass, with the width of str
+// as described for writeWidth.
+func (s *state) writeClassWidth(c class, str string, width int) {
+	var esc string
+	if s.theme != nil {
+		esc = s.theme.escape(c)
+	}
+	if esc == "" {
+		s.writeWidth(str, width)
+		return
+	}
+	if !s.room(str) {
+		return
+	}
+	s.writeEscape(esc)
+	s.writeWidth(str, width)
+	s.writeEscape(colorReset)
+}
+
+// room reports whether str can be written within MaxBytes and MaxLines.
+// If not, it stops formatting.
+func (s *state) room(str string) bool {
+	if s.err != nil {
+		return false
+	}
+	if (s.maxBytes > 0 && s.nbytes+len(str) > s.maxBytes) ||
+		(s.maxLines > 0 && s.lines+strings.Count(str, "\n") >= s.maxLines) {
+		s.err = errBudget
+		return false
+	}
+	return true
+}
+
+// writeEscape writes an escape sequence, which takes no columns.
+func (s *state) writeEscape(esc string) {
+	if s.err != nil || s.discard {
+		return
 	}
-	s.write(str)
+	s.buf = append(s.buf, esc...)
 }
 
 func (s *state) write(str string) {
+	s.writeWidth(str, -1)
+}
+
+// writeWidth writes str. If width is non-negative, str contains no
+// newlines or tabs and occupies width columns; otherwise writeWidth
+// measures it.
+func (s *state) writeWidth(str string, width int) {
 	if s.err != nil {
 		return
 	}
-	_, s.err = io.WriteString(s.w, str)
-	// Adjust col. Assume one column per character.
-	if i := strings.LastIndex(str, "\n"); i >= 0 {
-		s.col = len(str) - i - 1
+	if s.failOnNewline && strings.IndexByte(str, '\n') >= 0 {
+		s.err = errNewline
+		return
+	}
+	if !s.room(str) {
+		return
+	}
+	s.nbytes += len(str)
+	s.lines += strings.Count(str, "\n")
+	if !s.discard {
+		s.buf = append(s.buf, str...)
+		if s.out != nil && len(s.buf) >= s.ChunkSize {
+			s.flush()
+		}
+	}
+	// Adjust col.
+	if width >= 0 {
+		s.col += width
+	} else if i := strings.LastIndex(str, "\n"); i >= 0 {
+		s.col = s.advance(0, str[i+1:])
 	} else {
-		s.col += len(str)
+		s.col = s.advance(s.col, str)
+	}
+}
+
+// flush writes buf to s.out, for ChunkSize.
+func (s *state) flush() {
+	s.out.Write(s.buf)
+	s.buf = s.buf[:0]
+	if s.out.err != nil {
+		s.err = s.out.err
+	}
+}
+
+// advance returns the column after writing str, which contains no
+// newlines, starting at col. A tab advances to the next tab stop.
+func (s *state) advance(col int, str string) int {
+	for {
+		i := strings.IndexByte(str, '\t')
+		if i < 0 {
+			return col + s.width(str)
+		}
+		col += s.width(str[:i])
+		tw := s.tabWidth()
+		col = (col/tw + 1) * tw
+		str = str[i+1:]
+	}
+}
+
+// tabWidth returns the columns between tab stops, observing the default.
+func (f *Formatter) tabWidth() int {
+	if f.TabWidth <= 0 {
+		return 8
 	}
+	return f.TabWidth
 }
 
-// TODO: call Equal method if any.
-// TODO: recurse into slices, arrays, pointers?
+// width returns the number of columns that str occupies.
+func (f *Formatter) width(str string) int {
+	if f.WidthFunc != nil {
+		return f.WidthFunc(str)
+	}
+	return utf8.RuneCountInString(str)
+}
+
+// compareValues orders two map keys, for deterministic output.
+// Values of different types are ordered by type name.
+// Values of the same type are compared by their Compare, Less or Equal
+// methods if they have them, and otherwise structurally.
 func compareValues(v1, v2 reflect.Value) int {
+	c := comparer{seen: map[[2]uintptr]bool{}}
+	return c.compare(v1, v2)
+}
+
+// maxCompareDepth bounds the recursion of compareValues.
+const maxCompareDepth = 20
+
+// A comparer compares values structurally.
+// Like a differ, it stops at a maximum depth and tracks the pairs of
+// pointers it is following, so it terminates on any value.
+type comparer struct {
+	depth int
+	seen  map[[2]uintptr]bool // pairs of pointers currently being compared
+}
+
+func (c *comparer) compare(v1, v2 reflect.Value) int {
 	if !v1.IsValid() && !v2.IsValid() {
 		return 0
 	}
@@ -366,6 +2518,9 @@ func compareValues(v1, v2 reflect.Value) int {
 	if v2.Kind() == reflect.Interface {
 		v2 = v2.Elem()
 	}
+	if !v1.IsValid() || !v2.IsValid() {
+		return c.compare(v1, v2)
+	}
 
 	if t1, t2 := v1.Type(), v2.Type(); t1 != t2 {
 		return cmp.Compare(t1.String(), t2.String())
@@ -379,9 +2534,104 @@ func compareValues(v1, v2 reflect.Value) int {
 	if v1.CanFloat() {
 		return cmp.Compare(v1.Float(), v2.Float())
 	}
-	// Either string or not cmp.Ordered; do our best.
-	// TODO: prevent Sprint from blowing stack on non-pointer cycles.
-	return cmp.Compare(fmt.Sprint(v1), fmt.Sprint(v2))
+	if r, ok := compareMethod(v1, v2); ok {
+		return r
+	}
+	if c.depth >= maxCompareDepth {
+		return 0
+	}
+	c.depth++
+	defer func() { c.depth-- }()
+
+	switch v1.Kind() {
+	case reflect.String:
+		return cmp.Compare(v1.String(), v2.String())
+	case reflect.Bool:
+		return cmp.Compare(boolInt(v1.Bool()), boolInt(v2.Bool()))
+	case reflect.Complex64, reflect.Complex128:
+		z1, z2 := v1.Complex(), v2.Complex()
+		return cmp.Or(cmp.Compare(real(z1), real(z2)), cmp.Compare(imag(z1), imag(z2)))
+	case reflect.Pointer:
+		if v1.IsNil() || v2.IsNil() || v1.Pointer() == v2.Pointer() {
+			return cmp.Compare(boolInt(!v1.IsNil()), boolInt(!v2.IsNil()))
+		}
+		key := [2]uintptr{v1.Pointer(), v2.Pointer()}
+		if c.seen[key] {
+			// A cycle: fall back to the addresses.
+			return cmp.Compare(key[0], key[1])
+		}
+		c.seen[key] = true
+		defer delete(c.seen, key)
+		return c.compare(v1.Elem(), v2.Elem())
+	case reflect.Struct:
+		for i := range v1.NumField() {
+			if r := c.compare(v1.Field(i), v2.Field(i)); r != 0 {
+				return r
+			}
+		}
+		return 0
+	case reflect.Array, reflect.Slice:
+		for i := range min(v1.Len(), v2.Len()) {
+			if r := c.compare(v1.Index(i), v2.Index(i)); r != 0 {
+				return r
+			}
+		}
+		return cmp.Compare(v1.Len(), v2.Len())
+	case reflect.Map:
+		return cmp.Compare(v1.Len(), v2.Len())
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return cmp.Compare(v1.Pointer(), v2.Pointer())
+	default:
+		return 0
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// compareMethod compares v1 and v2, which have the same type, using a method
+// of that type: Compare(T) int, Less(T) bool or Equal(T) bool.
+// It reports whether a method determined the result.
+// An Equal method can only establish that v1 and v2 are equal.
+func compareMethod(v1, v2 reflect.Value) (int, bool) {
+	if !v1.CanInterface() || !v2.CanInterface() {
+		return 0, false // from an unexported field; methods can't be called
+	}
+	t := v1.Type()
+	if t.Kind() == reflect.Pointer && (v1.IsNil() || v2.IsNil()) {
+		return 0, false
+	}
+	// call calls the named method of x with argument y, if it has the right
+	// signature.
+	call := func(name string, out reflect.Type, x, y reflect.Value) (reflect.Value, bool) {
+		m, ok := t.MethodByName(name)
+		if !ok || m.Type.NumIn() != 2 || m.Type.In(1) != t ||
+			m.Type.NumOut() != 1 || m.Type.Out(0) != out {
+			return reflect.Value{}, false
+		}
+		return x.Method(m.Index).Call([]reflect.Value{y})[0], true
+	}
+	intType, boolType := reflect.TypeFor[int](), reflect.TypeFor[bool]()
+	if r, ok := call("Compare", intType, v1, v2); ok {
+		return cmp.Compare(r.Int(), 0), true
+	}
+	if r, ok := call("Less", boolType, v1, v2); ok {
+		if r.Bool() {
+			return -1, true
+		}
+		if r, _ := call("Less", boolType, v2, v1); r.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	if r, ok := call("Equal", boolType, v1, v2); ok && r.Bool() {
+		return 0, true
+	}
+	return 0, false
 }
 
 // isOrdered reports whether values of type t can be compared with <, >, etc.
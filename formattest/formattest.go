@@ -0,0 +1,50 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package formattest provides test helpers built on the format package.
+package formattest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jba/format"
+)
+
+// Equal reports whether got and want are equal according to f's Diff method.
+// If not, it marks the test as failed with a description of the differences,
+// one per line.
+// If f is nil, the default Formatter is used.
+func Equal(t testing.TB, f *format.Formatter, got, want any) bool {
+	t.Helper()
+	if f == nil {
+		f = format.New()
+	}
+	d := f.Diff(got, want)
+	if d == "" {
+		return true
+	}
+	t.Errorf("got and want differ:\n%s", indent(d))
+	return false
+}
+
+// NotEqual reports whether got and want differ according to f's Diff method.
+// If not, it marks the test as failed and displays got, formatted with f.
+// If f is nil, the default Formatter is used.
+func NotEqual(t testing.TB, f *format.Formatter, got, want any) bool {
+	t.Helper()
+	if f == nil {
+		f = format.New()
+	}
+	if f.Diff(got, want) != "" {
+		return true
+	}
+	t.Errorf("got and want are equal:\n%s", indent(f.Sprint(got)))
+	return false
+}
+
+// indent indents each line of s, so that it stands out in test output.
+func indent(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return "\t" + strings.ReplaceAll(s, "\n", "\n\t")
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package formattest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jba/format"
+)
+
+// recorder is a testing.TB that records failures.
+type recorder struct {
+	testing.TB
+	msgs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(f string, args ...any) {
+	r.msgs = append(r.msgs, fmt.Sprintf(f, args...))
+}
+
+type point struct{ X, Y int }
+
+func TestEqual(t *testing.T) {
+	r := &recorder{TB: t}
+	if !Equal(r, nil, point{1, 2}, point{1, 2}) || len(r.msgs) > 0 {
+		t.Errorf("equal values: got failure %q", r.msgs)
+	}
+	if Equal(r, nil, point{1, 2}, point{3, 4}) {
+		t.Error("unequal values: got true")
+	}
+	want := "got and want differ:\n\tX: got 1, want 3\n\tY: got 2, want 4"
+	if len(r.msgs) != 1 || r.msgs[0] != want {
+		t.Errorf("got %q, want %q", r.msgs, want)
+	}
+
+	// The Formatter's rules apply.
+	r = &recorder{TB: t}
+	f := format.New().IgnoreFields(point{}, "Y")
+	if !Equal(r, f, point{1, 2}, point{1, 4}) {
+		t.Errorf("ignored field: got failure %q", r.msgs)
+	}
+}
+
+func TestNotEqual(t *testing.T) {
+	r := &recorder{TB: t}
+	if !NotEqual(r, nil, 1, 2) || len(r.msgs) > 0 {
+		t.Errorf("unequal values: got failure %q", r.msgs)
+	}
+	f := format.New(format.WithCompact(true), format.WithOmitPackage(true))
+	if NotEqual(r, f, point{1, 2}, point{1, 2}) {
+		t.Error("equal values: got true")
+	}
+	want := "got and want are equal:\n\tpoint{X: 1, Y: 2}"
+	if len(r.msgs) != 1 || r.msgs[0] != want {
+		t.Errorf("got %q, want %q", r.msgs, want)
+	}
+}
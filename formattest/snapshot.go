@@ -0,0 +1,91 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package formattest
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jba/format"
+	"golang.org/x/tools/txtar"
+)
+
+// update is the -formattest.update flag. Its name is qualified so that it
+// doesn't collide with an -update flag defined by the test that imports
+// this package.
+var update = flag.Bool("formattest.update", false, "update formattest snapshot files")
+
+// testdata is the directory holding snapshot files. It is a variable for testing.
+var testdata = "testdata"
+
+// Snapshot compares x, formatted by f, to the snapshot called name, and
+// marks the test as failed if they differ.
+// If f is nil, the default Formatter is used.
+// The snapshots of a test are stored as the sections of a txtar archive in
+// the testdata directory, named after the test.
+// Because txtar ends every section with a newline, a final newline is
+// ignored when comparing.
+// Run the test with the -formattest.update flag to create or update its
+// snapshots, as in
+//
+//	go test -run TestName -formattest.update
+func Snapshot(t testing.TB, f *format.Formatter, name string, x any) {
+	t.Helper()
+	if f == nil {
+		f = format.New()
+	}
+	got := f.Sprint(x)
+	file := filepath.Join(testdata, snapshotFile(t.Name()))
+	ar, err := txtar.ParseFile(file)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatal(err)
+		}
+		ar = &txtar.Archive{}
+	}
+	i := findFile(ar, name)
+	if *update {
+		if i < 0 {
+			ar.Files = append(ar.Files, txtar.File{Name: name})
+			i = len(ar.Files) - 1
+		}
+		ar.Files[i].Data = []byte(got)
+		if err := os.MkdirAll(testdata, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, txtar.Format(ar), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if i < 0 {
+		t.Errorf("no snapshot %q in %s; run with -formattest.update to create it", name, file)
+		return
+	}
+	got = strings.TrimSuffix(got, "\n")
+	if want := strings.TrimSuffix(string(ar.Files[i].Data), "\n"); got != want {
+		t.Errorf("snapshot %q in %s does not match; run with -formattest.update to update it\ngot:\n%s\nwant:\n%s",
+			name, file, got, want)
+	}
+}
+
+// snapshotFile returns the name of the snapshot file for a test.
+// Subtest names are separated by underscores instead of slashes.
+func snapshotFile(testName string) string {
+	return strings.ReplaceAll(testName, "/", "_") + ".txt"
+}
+
+func findFile(ar *txtar.Archive, name string) int {
+	for i, f := range ar.Files {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package formattest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jba/format"
+)
+
+func TestSnapshot(t *testing.T) {
+	defer func(d string, u bool) { testdata, *update = d, u }(testdata, *update)
+	testdata = t.TempDir()
+	p := point{1, 2}
+	f := format.New(format.WithOmitPackage(true))
+	compact := format.New(format.WithOmitPackage(true), format.WithCompact(true))
+
+	// A missing snapshot fails.
+	r := &recorder{TB: t}
+	Snapshot(r, f, "p", p)
+	if len(r.msgs) != 1 || !strings.HasPrefix(r.msgs[0], `no snapshot "p"`) {
+		t.Fatalf("missing: got %q", r.msgs)
+	}
+
+	*update = true
+	Snapshot(r, f, "p", p)
+	Snapshot(r, compact, "q", []int{1})
+	*update = false
+	data, err := os.ReadFile(filepath.Join(testdata, "TestSnapshot.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "-- p --\npoint{\n    X: 1\n    Y: 2\n}\n-- q --\n[]{1}\n"
+	if got := string(data); got != want {
+		t.Errorf("file:\ngot\n%s\nwant\n%s", got, want)
+	}
+
+	r = &recorder{TB: t}
+	Snapshot(r, f, "p", p)
+	if len(r.msgs) > 0 {
+		t.Errorf("matching: got %q", r.msgs)
+	}
+	// Compact output has no final newline, but its section does.
+	Snapshot(r, compact, "q", []int{1})
+	if len(r.msgs) > 0 {
+		t.Errorf("matching compact: got %q", r.msgs)
+	}
+	Snapshot(r, f, "p", point{1, 3})
+	if len(r.msgs) != 1 || !strings.Contains(r.msgs[0], "does not match") {
+		t.Errorf("mismatch: got %q", r.msgs)
+	}
+}
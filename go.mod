@@ -3,3 +3,7 @@ module github.com/jba/format
 go 1.23
 
 require golang.org/x/tools v0.25.0
+
+require github.com/google/go-cmp v0.7.0
+
+require google.golang.org/protobuf v1.34.2
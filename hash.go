@@ -0,0 +1,30 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"hash/fnv"
+	"reflect"
+)
+
+// Hash calls [Formatter.Hash] with the default Formatter.
+func Hash(x any) uint64 { return New().Hash(x) }
+
+// Hash returns a 64-bit FNV-1a hash of x as f formats it with
+// Deterministic and Compact set, so that tests and caches can detect
+// a changed value without storing its formatted form.
+// The hash is the same for equal values in every run, with the exceptions
+// described for Deterministic. It reflects f's other settings, like
+// MaxDepth and IgnoreFields, so a change hidden by them is not detected.
+func (f *Formatter) Hash(x any) uint64 {
+	c := *f
+	c.Deterministic = true
+	c.Compact = true
+	c.MaxWidth = 0
+	c.Prefix = ""
+	c.HeaderFunc = nil
+	h := fnv.New64a()
+	h.Write(c.appendBody(nil, reflect.ValueOf(x), nil))
+	return h.Sum64()
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "testing"
+
+func TestHash(t *testing.T) {
+	newTeam := func(score int) *team {
+		return &team{Players: []Player{{Name: "Al", Score: score}}, M: map[string]int{"a": 1, "b": 2}}
+	}
+	h := Hash(newTeam(1))
+	if got := Hash(newTeam(1)); got != h {
+		t.Errorf("equal values: got %x, want %x", got, h)
+	}
+	if got := Hash(newTeam(2)); got == h {
+		t.Error("different values have the same hash")
+	}
+	// Changes to ignored fields are not detected.
+	f := New().IgnoreFields(Player{}, "Score")
+	if f.Hash(newTeam(1)) != f.Hash(newTeam(2)) {
+		t.Error("ignored field changed the hash")
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"html"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// SprintHTML calls [Formatter.SprintHTML] with the default Formatter.
+func SprintHTML(x any) string { return New().SprintHTML(x) }
+
+// SprintHTML formats x as an HTML tree of nested <details> elements, one for
+// each struct, slice, array and map, so that large values can be explored
+// in a browser.
+// The <summary> of each element is the start of its value on one line.
+// Other values, and values printed by custom functions or methods,
+// are leaves of the tree.
+// The tree observes f's rules for omitting fields and types, and its
+// MaxDepth and MaxElements limits, using the same markers as Sprint.
+// Elements are nested in <ul> lists; the outermost has class "format",
+// for styling.
+func (f *Formatter) SprintHTML(x any) string {
+	h := &htmlState{
+		tree: tree{Formatter: f, maxDepth: f.maxDepth()},
+		seen: map[ptrKey]bool{},
+	}
+	h.WriteString(`<ul class="format">` + "\n")
+	h.node("", reflect.ValueOf(x))
+	h.WriteString("</ul>\n")
+	return h.String()
+}
+
+// FprintHTML writes the HTML tree for x to w. See [Formatter.SprintHTML].
+func (f *Formatter) FprintHTML(w io.Writer, x any) error {
+	_, err := io.WriteString(w, f.SprintHTML(x))
+	return err
+}
+
+type htmlState struct {
+	tree
+	strings.Builder
+	seen map[ptrKey]bool // pointers on the current path, to detect cycles
+}
+
+// node writes v as a list item. A non-empty label, like a field name,
+// precedes its value.
+func (h *htmlState) node(label string, v reflect.Value) {
+	if label != "" {
+		label = html.EscapeString(label) + ": "
+	}
+	if v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if h.depth > h.maxDepth {
+		h.leaf(label, h.markers().MaxDepth)
+		return
+	}
+	if !h.expands(v) {
+		h.leaf(label, h.sprintCompact(v))
+		return
+	}
+	elem := v // the value whose elements are listed
+	if v.Kind() == reflect.Pointer {
+		p := pointerKey(v)
+		if h.seen[p] {
+			h.leaf(label, h.markers().Cycle)
+			return
+		}
+		h.seen[p] = true
+		defer delete(h.seen, p)
+		elem = v.Elem()
+	}
+	h.WriteString("<li><details><summary>" + label +
+		html.EscapeString(h.summary(v)) + "</summary>\n<ul>\n")
+	h.depth++
+	truncated := h.children(elem, func(label string, v reflect.Value, tag tagOptions) {
+		if tag.special() {
+			h.leaf(html.EscapeString(label)+": ", h.sprintField(v, tag))
+		} else {
+			h.node(label, v)
+		}
+	})
+	if truncated {
+		h.leaf("", h.markers().Truncated)
+	}
+	h.depth--
+	h.WriteString("</ul>\n</details></li>\n")
+}
+
+func (h *htmlState) leaf(label, text string) {
+	h.WriteString("<li>" + label + html.EscapeString(text) + "</li>\n")
+}
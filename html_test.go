@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "testing"
+
+func TestSprintHTML(t *testing.T) {
+	f := New(WithOmitPackage(true), WithMaxElements(1))
+	n := &node{I: 1}
+	n.Next = n
+	for _, test := range []struct {
+		in   any
+		want string
+	}{
+		{3, "<ul class=\"format\">\n<li>3</li>\n</ul>\n"},
+		{
+			team{Players: []Player{{Name: "<Al>"}, {Name: "Bo"}}, M: map[string]int{"a": 1}},
+			`<ul class="format">
+<li><details><summary>team{Players: []{Player{Name: &#34;&lt;Al&gt;&#34;}, ...}, M: {&#34;a&#34;: 1}}</summary>
+<ul>
+<li><details><summary>Players: []{Player{Name: &#34;&lt;Al&gt;&#34;}, ...}</summary>
+<ul>
+<li><details><summary>[0]: Player{Name: &#34;&lt;Al&gt;&#34;}</summary>
+<ul>
+<li>Name: &#34;&lt;Al&gt;&#34;</li>
+</ul>
+</details></li>
+<li>...</li>
+</ul>
+</details></li>
+<li><details><summary>M: {&#34;a&#34;: 1}</summary>
+<ul>
+<li>&#34;a&#34;: 1</li>
+</ul>
+</details></li>
+</ul>
+</details></li>
+</ul>
+`,
+		},
+		{
+			n,
+			`<ul class="format">
+<li><details><summary>&amp;node{I: 1, Next: &lt;cycle&gt;}</summary>
+<ul>
+<li>I: 1</li>
+<li>Next: &lt;cycle&gt;</li>
+</ul>
+</details></li>
+</ul>
+`,
+		},
+		{
+			tagged{Secret: "s", Flags: 10},
+			`<ul class="format">
+<li><details><summary>tagged{Secret: &lt;redacted&gt;, Flags: 0xa}</summary>
+<ul>
+<li>Secret: &lt;redacted&gt;</li>
+<li>Flags: 0xa</li>
+</ul>
+</details></li>
+</ul>
+`,
+		},
+	} {
+		if got := f.SprintHTML(test.in); got != test.want {
+			t.Errorf("%v:\ngot\n%s\nwant\n%s", test.in, got, test.want)
+		}
+	}
+}
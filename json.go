@@ -0,0 +1,82 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+var rawMessageType = reflect.TypeFor[json.RawMessage]()
+
+// printRawMessage prints a json.RawMessage that holds valid JSON as
+// that JSON, with printJSON. It reports whether it printed v.
+func (s *state) printRawMessage(v reflect.Value) bool {
+	if v.Type() != rawMessageType || s.GoSyntax {
+		return false
+	}
+	return s.printJSON(v.Bytes())
+}
+
+// printJSON prints b, which should hold JSON, re-indented to line up
+// with the surrounding output, like
+//
+//	Body: {
+//	    "id": 1,
+//	    "tags": ["a"]
+//	}
+//
+// In compact mode, it prints b on one line.
+// It reports whether b was valid JSON; if not, it prints nothing.
+func (s *state) printJSON(b []byte) bool {
+	var buf bytes.Buffer
+	var err error
+	if s.compact {
+		err = json.Compact(&buf, b)
+	} else {
+		err = json.Indent(&buf, b, "", s.indent)
+	}
+	if err != nil {
+		return false
+	}
+	for i, line := range strings.Split(buf.String(), "\n") {
+		if i > 0 {
+			s.write("\n")
+		}
+		s.prUnbroken(stringClass, line)
+	}
+	return true
+}
+
+// A jsonTag holds the parts of a field's encoding/json struct tag
+// that JSONNames uses.
+type jsonTag struct {
+	name      string // the field's key: the tag's name, or else the field's name
+	omit      bool   // the tag is "-"
+	omitEmpty bool   // the tag has the omitempty or omitzero option
+}
+
+func parseJSONTag(sf reflect.StructField) jsonTag {
+	jt := jsonTag{name: sf.Name}
+	tag, ok := sf.Tag.Lookup("json")
+	if !ok {
+		return jt
+	}
+	if tag == "-" {
+		jt.omit = true
+		return jt
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name != "" {
+		jt.name = name
+	}
+	for _, o := range strings.Split(opts, ",") {
+		if o == "omitempty" || o == "omitzero" {
+			jt.omitEmpty = true
+		}
+	}
+	return jt
+}
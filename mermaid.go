@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SprintMermaid calls [Formatter.SprintMermaid] with the default Formatter.
+func SprintMermaid(x any) string { return New().SprintMermaid(x) }
+
+// SprintMermaid formats x as a Mermaid flowchart, suitable for a
+// ```mermaid block in Markdown.
+// Each struct, slice, array and map is a node, labeled with the start of its
+// value on one line. Edges lead from a value to the composite values it
+// contains, and are labeled with field names, indexes and map keys.
+// Each pointer is a single node, however often it is reached,
+// so the graph shows sharing and cycles.
+// Like [Formatter.SprintHTML], the graph observes f's rules for omitting
+// fields and types, and its MaxDepth and MaxElements limits.
+func (f *Formatter) SprintMermaid(x any) string {
+	m := &mermaidState{
+		tree:  tree{Formatter: f, maxDepth: f.maxDepth()},
+		nodes: map[ptrKey]string{},
+	}
+	m.WriteString("graph TD\n")
+	m.node(reflect.ValueOf(x))
+	return m.String()
+}
+
+type mermaidState struct {
+	tree
+	strings.Builder
+	nodes map[ptrKey]string // IDs of the nodes for pointers
+	n     int               // number of nodes
+}
+
+// node writes the node for v, and the nodes and edges below it.
+// It returns the node's ID.
+func (m *mermaidState) node(v reflect.Value) string {
+	if v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	elem := v
+	if m.expands(v) && v.Kind() == reflect.Pointer {
+		if id, ok := m.nodes[pointerKey(v)]; ok {
+			return id
+		}
+		elem = v.Elem()
+	}
+	m.n++
+	id := fmt.Sprintf("n%d", m.n)
+	if elem != v {
+		m.nodes[pointerKey(v)] = id
+	}
+	m.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", id, mermaidEscape(m.summary(v))))
+	if !m.expands(v) || m.depth >= m.maxDepth {
+		return id
+	}
+	m.depth++
+	defer func() { m.depth-- }()
+	truncated := m.children(elem, func(label string, v reflect.Value, tag tagOptions) {
+		if tag.special() {
+			// Shown in the summary.
+			return
+		}
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if !m.expands(v) {
+			return
+		}
+		child := m.node(v)
+		m.WriteString(fmt.Sprintf("    %s -->|\"%s\"| %s\n", id, mermaidEscape(label), child))
+	})
+	if truncated {
+		m.n++
+		m.WriteString(fmt.Sprintf("    %s --> n%d[\"%s\"]\n", id, m.n, mermaidEscape(m.markers().Truncated)))
+	}
+	return id
+}
+
+// mermaidEscape escapes s for a quoted Mermaid label.
+var mermaidEscape = strings.NewReplacer(
+	`"`, "#quot;",
+	"<", "#lt;",
+	">", "#gt;",
+	"#", "#35;",
+).Replace
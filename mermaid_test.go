@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "testing"
+
+func TestSprintMermaid(t *testing.T) {
+	f := New(WithOmitPackage(true), WithMaxElements(2))
+	shared := &node{I: 2}
+	cyc := &node{I: 1}
+	cyc.Next = cyc
+	d := &derived{Base: Base{ID: 1}, Name: "d"}
+	for _, test := range []struct {
+		in   any
+		want string
+	}{
+		{1, "graph TD\n    n1[\"1\"]\n"},
+		{
+			team{Players: []Player{{Name: "Al"}, {Name: "Bo"}, {Name: "Cy"}}},
+			`graph TD
+    n1["team{Players: []{Player{Name: #quot;Al#quot;}, Player{Name: #quot;Bo#quot;}, ......(truncated)"]
+    n2["[]{Player{Name: #quot;Al#quot;}, Player{Name: #quot;Bo#quot;}, ...}"]
+    n3["Player{Name: #quot;Al#quot;}"]
+    n2 -->|"[0]"| n3
+    n4["Player{Name: #quot;Bo#quot;}"]
+    n2 -->|"[1]"| n4
+    n2 --> n5["..."]
+    n1 -->|"Players"| n2
+`,
+		},
+		{
+			[]*node{shared, shared, cyc},
+			`graph TD
+    n1["[]{&node{I: 2}, &node{I: 2}, ...}"]
+    n2["&node{I: 2}"]
+    n1 -->|"[0]"| n2
+    n1 -->|"[1]"| n2
+    n1 --> n3["..."]
+`,
+		},
+		{
+			// A pointer to a struct and to its first field are different nodes.
+			[]any{d, &d.Base},
+			`graph TD
+    n1["[]{&derived{Base: Base{ID: 1}, Name: #quot;d#quot;}, &Base{ID: 1}}"]
+    n2["&derived{Base: Base{ID: 1}, Name: #quot;d#quot;}"]
+    n3["Base{ID: 1}"]
+    n2 -->|"Base"| n3
+    n1 -->|"[0]"| n2
+    n4["&Base{ID: 1}"]
+    n1 -->|"[1]"| n4
+`,
+		},
+		{
+			cyc,
+			`graph TD
+    n1["&node{I: 1, Next: #lt;cycle#gt;}"]
+    n1 -->|"Next"| n1
+`,
+		},
+	} {
+		if got := f.SprintMermaid(test.in); got != test.want {
+			t.Errorf("%v:\ngot\n%s\nwant\n%s", test.in, got, test.want)
+		}
+	}
+}
+
+func TestSprintMermaidFlatten(t *testing.T) {
+	f := New(WithOmitPackage(true), WithFlatten(true))
+	type Team = team
+	in := struct{ *Team }{&team{M: map[string]int{"a": 1}}}
+	want := `graph TD
+    n1["struct { *team }{M: {#quot;a#quot;: 1}}"]
+    n2["{#quot;a#quot;: 1}"]
+    n1 -->|"M"| n2
+`
+	if got := f.SprintMermaid(in); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
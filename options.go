@@ -0,0 +1,380 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// An Option configures a Formatter.
+// Options are an alternative to setting a Formatter's fields directly;
+// they make it easy to build and share presets.
+type Option func(*Formatter)
+
+// WithShowZero returns an Option that sets [Formatter.ShowZero].
+func WithShowZero(b bool) Option { return func(f *Formatter) { f.ShowZero = b } }
+
+// WithMaxWidth returns an Option that sets [Formatter.MaxWidth].
+func WithMaxWidth(n int) Option { return func(f *Formatter) { f.MaxWidth = n } }
+
+// WithCompact returns an Option that sets [Formatter.Compact].
+func WithCompact(b bool) Option { return func(f *Formatter) { f.Compact = b } }
+
+// WithIndent returns an Option that sets [Formatter.Indent].
+func WithIndent(s string) Option { return func(f *Formatter) { f.Indent = s } }
+
+// WithTabWidth returns an Option that sets [Formatter.TabWidth].
+func WithTabWidth(n int) Option { return func(f *Formatter) { f.TabWidth = n } }
+
+// WithIndentGuides returns an Option that sets [Formatter.IndentGuides].
+func WithIndentGuides(b bool) Option { return func(f *Formatter) { f.IndentGuides = b } }
+
+// WithSoftDepth returns an Option that sets [Formatter.SoftDepth].
+func WithSoftDepth(n int) Option { return func(f *Formatter) { f.SoftDepth = n } }
+
+// WithChunkSize returns an Option that sets [Formatter.ChunkSize].
+func WithChunkSize(n int) Option { return func(f *Formatter) { f.ChunkSize = n } }
+
+// WithParallel returns an Option that sets [Formatter.Parallel].
+func WithParallel(n int) Option { return func(f *Formatter) { f.Parallel = n } }
+
+// WithMaxDepth returns an Option that sets [Formatter.MaxDepth].
+func WithMaxDepth(n int) Option { return func(f *Formatter) { f.MaxDepth = n } }
+
+// WithMaxElements returns an Option that sets [Formatter.MaxElements].
+func WithMaxElements(n int) Option { return func(f *Formatter) { f.MaxElements = n } }
+
+// WithTailElements returns an Option that sets [Formatter.TailElements].
+func WithTailElements(n int) Option { return func(f *Formatter) { f.TailElements = n } }
+
+// WithOmitPackage returns an Option that sets [Formatter.OmitPackage].
+func WithOmitPackage(b bool) Option { return func(f *Formatter) { f.OmitPackage = b } }
+
+// WithTypeNames returns an Option that sets [Formatter.TypeNames].
+func WithTypeNames(m TypeNameMode) Option { return func(f *Formatter) { f.TypeNames = m } }
+
+// WithLocalPackage returns an Option that sets [Formatter.LocalPackage].
+func WithLocalPackage(pkgPath string) Option { return func(f *Formatter) { f.LocalPackage = pkgPath } }
+
+// WithUseStringer returns an Option that sets [Formatter.UseStringer].
+func WithUseStringer(b bool) Option { return func(f *Formatter) { f.UseStringer = b } }
+
+// WithUseGoStringer returns an Option that sets [Formatter.UseGoStringer].
+func WithUseGoStringer(b bool) Option { return func(f *Formatter) { f.UseGoStringer = b } }
+
+// WithUseError returns an Option that sets [Formatter.UseError].
+func WithUseError(b bool) Option { return func(f *Formatter) { f.UseError = b } }
+
+// WithErrorTree returns an Option that sets [Formatter.ErrorTree].
+func WithErrorTree(b bool) Option { return func(f *Formatter) { f.ErrorTree = b } }
+
+// WithGoSyntax returns an Option that sets [Formatter.GoSyntax].
+func WithGoSyntax(b bool) Option { return func(f *Formatter) { f.GoSyntax = b } }
+
+// WithColor returns an Option that sets [Formatter.Color].
+func WithColor(b bool) Option { return func(f *Formatter) { f.Color = b } }
+
+// WithTheme returns an Option that sets [Formatter.Theme].
+func WithTheme(t *Theme) Option { return func(f *Formatter) { f.Theme = t } }
+
+// WithStrict returns an Option that sets [Formatter.Strict].
+func WithStrict(b bool) Option { return func(f *Formatter) { f.Strict = b } }
+
+// WithMarkers returns an Option that sets [Formatter.Markers].
+func WithMarkers(m Markers) Option { return func(f *Formatter) { f.Markers = m } }
+
+// WithAssumeAcyclic returns an Option that sets [Formatter.AssumeAcyclic].
+func WithAssumeAcyclic(b bool) Option { return func(f *Formatter) { f.AssumeAcyclic = b } }
+
+// WithShowSharing returns an Option that sets [Formatter.ShowSharing].
+func WithShowSharing(b bool) Option { return func(f *Formatter) { f.ShowSharing = b } }
+
+// WithMaxStringLen returns an Option that sets [Formatter.MaxStringLen].
+func WithMaxStringLen(n int) Option { return func(f *Formatter) { f.MaxStringLen = n } }
+
+// WithBytesMode returns an Option that sets [Formatter.BytesMode].
+func WithBytesMode(m BytesMode) Option { return func(f *Formatter) { f.BytesMode = m } }
+
+// WithSmart returns an Option that sets [Formatter.Smart].
+func WithSmart(b bool) Option { return func(f *Formatter) { f.Smart = b } }
+
+// WithShowLen returns an Option that sets [Formatter.ShowLen].
+func WithShowLen(b bool) Option { return func(f *Formatter) { f.ShowLen = b } }
+
+// WithDeterministic returns an Option that sets [Formatter.Deterministic].
+func WithDeterministic(b bool) Option { return func(f *Formatter) { f.Deterministic = b } }
+
+// WithShowAddresses returns an Option that sets [Formatter.ShowAddresses].
+func WithShowAddresses(b bool) Option { return func(f *Formatter) { f.ShowAddresses = b } }
+
+// WithPullSeqs returns an Option that sets [Formatter.PullSeqs].
+func WithPullSeqs(b bool) Option { return func(f *Formatter) { f.PullSeqs = b } }
+
+// WithOrderedMaps returns an Option that sets [Formatter.OrderedMaps].
+func WithOrderedMaps(b bool) Option { return func(f *Formatter) { f.OrderedMaps = b } }
+
+// WithPointerLabels returns an Option that sets [Formatter.PointerLabels].
+func WithPointerLabels(b bool) Option { return func(f *Formatter) { f.PointerLabels = b } }
+
+// WithShowNil returns an Option that sets [Formatter.ShowNil].
+func WithShowNil(b bool) Option { return func(f *Formatter) { f.ShowNil = b } }
+
+// WithShowDynamicTypes returns an Option that sets [Formatter.ShowDynamicTypes].
+func WithShowDynamicTypes(b bool) Option { return func(f *Formatter) { f.ShowDynamicTypes = b } }
+
+// WithMaxBytes returns an Option that sets [Formatter.MaxBytes].
+func WithMaxBytes(n int) Option { return func(f *Formatter) { f.MaxBytes = n } }
+
+// WithMaxLines returns an Option that sets [Formatter.MaxLines].
+func WithMaxLines(n int) Option { return func(f *Formatter) { f.MaxLines = n } }
+
+// WithPrefix returns an Option that sets [Formatter.Prefix].
+func WithPrefix(s string) Option { return func(f *Formatter) { f.Prefix = s } }
+
+// WithHeaderFunc returns an Option that sets [Formatter.HeaderFunc].
+func WithHeaderFunc(fn func() string) Option { return func(f *Formatter) { f.HeaderFunc = fn } }
+
+// WithTimeFormat returns an Option that sets [Formatter.TimeFormat].
+func WithTimeFormat(s string) Option { return func(f *Formatter) { f.TimeFormat = s } }
+
+// WithRawTime returns an Option that sets [Formatter.RawTime].
+func WithRawTime(b bool) Option { return func(f *Formatter) { f.RawTime = b } }
+
+// WithRawSync returns an Option that sets [Formatter.RawSync].
+func WithRawSync(b bool) Option { return func(f *Formatter) { f.RawSync = b } }
+
+// WithRawSets returns an Option that sets [Formatter.RawSets].
+func WithRawSets(b bool) Option { return func(f *Formatter) { f.RawSets = b } }
+
+// WithTypeNameFunc returns an Option that sets [Formatter.TypeNameFunc].
+func WithTypeNameFunc(fn func(reflect.Type) string) Option {
+	return func(f *Formatter) { f.TypeNameFunc = fn }
+}
+
+// WithFieldOrder returns an Option that sets [Formatter.FieldOrder].
+func WithFieldOrder(cmp func(a, b reflect.StructField) int) Option {
+	return func(f *Formatter) { f.FieldOrder = cmp }
+}
+
+// WithMapKeyOrder returns an Option that sets [Formatter.MapKeyOrder].
+func WithMapKeyOrder(cmp func(a, b reflect.Value) int) Option {
+	return func(f *Formatter) { f.MapKeyOrder = cmp }
+}
+
+// WithFieldNameFunc returns an Option that sets [Formatter.FieldNameFunc].
+func WithFieldNameFunc(fn func(reflect.StructField) string) Option {
+	return func(f *Formatter) { f.FieldNameFunc = fn }
+}
+
+// WithAlignFields returns an Option that sets [Formatter.AlignFields].
+func WithAlignFields(b bool) Option { return func(f *Formatter) { f.AlignFields = b } }
+
+// WithTabAlign returns an Option that sets [Formatter.TabAlign].
+func WithTabAlign(b bool) Option { return func(f *Formatter) { f.TabAlign = b } }
+
+// WithTrailingCommas returns an Option that sets [Formatter.TrailingCommas].
+func WithTrailingCommas(b bool) Option { return func(f *Formatter) { f.TrailingCommas = b } }
+
+// WithPathComments returns an Option that sets [Formatter.PathComments].
+func WithPathComments(b bool) Option { return func(f *Formatter) { f.PathComments = b } }
+
+// WithFlatten returns an Option that sets [Formatter.Flatten].
+func WithFlatten(b bool) Option { return func(f *Formatter) { f.Flatten = b } }
+
+// WithUseIsZero returns an Option that sets [Formatter.UseIsZero].
+func WithUseIsZero(b bool) Option { return func(f *Formatter) { f.UseIsZero = b } }
+
+// WithJSONNames returns an Option that sets [Formatter.JSONNames].
+func WithJSONNames(b bool) Option { return func(f *Formatter) { f.JSONNames = b } }
+
+// WithWidthFunc returns an Option that sets [Formatter.WidthFunc].
+func WithWidthFunc(f func(string) int) Option { return func(g *Formatter) { g.WidthFunc = f } }
+
+// WithTable returns an Option that sets [Formatter.Table].
+func WithTable(b bool) Option { return func(f *Formatter) { f.Table = b } }
+
+// WithIntBase returns an Option that sets [Formatter.IntBase].
+func WithIntBase(base int) Option { return func(f *Formatter) { f.IntBase = base } }
+
+// WithAutoHex returns an Option that sets [Formatter.AutoHex].
+func WithAutoHex(b bool) Option { return func(f *Formatter) { f.AutoHex = b } }
+
+// WithFloatFormat returns an Option that sets [Formatter.FloatFormat].
+func WithFloatFormat(verb byte) Option { return func(f *Formatter) { f.FloatFormat = verb } }
+
+// WithFloatPrecision returns an Option that sets [Formatter.FloatPrecision].
+func WithFloatPrecision(n int) Option { return func(f *Formatter) { f.FloatPrecision = n } }
+
+// WithFloatTolerance returns an Option that sets [Formatter.FloatTolerance].
+func WithFloatTolerance(t float64) Option { return func(f *Formatter) { f.FloatTolerance = t } }
+
+// WithMultiline returns an Option that sets [Formatter.Multiline].
+func WithMultiline(m MultilineMode) Option { return func(f *Formatter) { f.Multiline = m } }
+
+// WithStringEscape returns an Option that sets [Formatter.StringEscape].
+func WithStringEscape(m EscapeMode) Option { return func(f *Formatter) { f.StringEscape = m } }
+
+// WithIgnoreFields returns an Option that calls [Formatter.IgnoreFields].
+func WithIgnoreFields(structval any, fields ...string) Option {
+	return func(f *Formatter) { f.IgnoreFields(structval, fields...) }
+}
+
+// WithIgnoreFieldPattern returns an Option that calls [Formatter.IgnoreFieldPattern].
+func WithIgnoreFieldPattern(patterns ...string) Option {
+	return func(f *Formatter) { f.IgnoreFieldPattern(patterns...) }
+}
+
+// WithFilterFields returns an Option that calls [Formatter.FilterFields].
+func WithFilterFields(keep func(reflect.StructField, reflect.Value) bool) Option {
+	return func(f *Formatter) { f.FilterFields(keep) }
+}
+
+// WithIgnoreTypes returns an Option that calls [Formatter.IgnoreTypes].
+func WithIgnoreTypes(vals ...any) Option {
+	return func(f *Formatter) { f.IgnoreTypes(vals...) }
+}
+
+// WithIgnoreMethods returns an Option that calls [Formatter.IgnoreMethods].
+func WithIgnoreMethods(vals ...any) Option {
+	return func(f *Formatter) { f.IgnoreMethods(vals...) }
+}
+
+// WithRedact returns an Option that calls [Formatter.Redact].
+func WithRedact(structval any, fields ...string) Option {
+	return func(f *Formatter) { f.Redact(structval, fields...) }
+}
+
+// WithRedactTypes returns an Option that calls [Formatter.RedactTypes].
+func WithRedactTypes(vals ...any) Option {
+	return func(f *Formatter) { f.RedactTypes(vals...) }
+}
+
+// WithShowZeroFields returns an Option that calls [Formatter.ShowZeroFields].
+func WithShowZeroFields(structval any, fields ...string) Option {
+	return func(f *Formatter) { f.ShowZeroFields(structval, fields...) }
+}
+
+// WithShowZeroTypes returns an Option that calls [Formatter.ShowZeroTypes].
+func WithShowZeroTypes(vals ...any) Option {
+	return func(f *Formatter) { f.ShowZeroTypes(vals...) }
+}
+
+// WithMaxDepthFor returns an Option that calls [Formatter.MaxDepthFor].
+func WithMaxDepthFor(structval any, n int) Option {
+	return func(f *Formatter) { f.MaxDepthFor(structval, n) }
+}
+
+// WithMaxElementsFor returns an Option that calls [Formatter.MaxElementsFor].
+func WithMaxElementsFor(val any, n int) Option {
+	return func(f *Formatter) { f.MaxElementsFor(val, n) }
+}
+
+// WithSortSlices returns an Option that calls [Formatter.SortSlices].
+func WithSortSlices(sorters ...any) Option {
+	return func(f *Formatter) { f.SortSlices(sorters...) }
+}
+
+// WithIgnorePaths returns an Option that calls [Formatter.IgnorePaths].
+func WithIgnorePaths(paths ...string) Option {
+	return func(f *Formatter) { f.IgnorePaths(paths...) }
+}
+
+// WithOnlyPaths returns an Option that calls [Formatter.OnlyPaths].
+func WithOnlyPaths(paths ...string) Option {
+	return func(f *Formatter) { f.OnlyPaths(paths...) }
+}
+
+// WithFormatFunc returns an Option that calls [FormatFunc].
+func WithFormatFunc[T any](fn func(T) string) Option {
+	return func(f *Formatter) { FormatFunc(f, fn) }
+}
+
+// WithTransform returns an Option that calls [Transform].
+func WithTransform[T, U any](fn func(T) U) Option {
+	return func(f *Formatter) { Transform(f, fn) }
+}
+
+// WithEnum returns an Option that calls [RegisterEnum].
+func WithEnum[T comparable](names map[T]string) Option {
+	return func(f *Formatter) { RegisterEnum(f, names) }
+}
+
+// WithFlags returns an Option that calls [RegisterFlags].
+func WithFlags[T integer](names map[T]string) Option {
+	return func(f *Formatter) { RegisterFlags(f, names) }
+}
+
+// Options returns an Option that applies all of opts, in order.
+func Options(opts ...Option) Option {
+	return func(f *Formatter) {
+		for _, o := range opts {
+			o(f)
+		}
+	}
+}
+
+// Clone returns a copy of f.
+// Changes to the copy, including calls to methods like [Formatter.IgnoreFields],
+// do not affect f.
+func (f *Formatter) Clone() *Formatter {
+	c := *f
+	if f.ignoreFields != nil {
+		c.ignoreFields = maps.Clone(f.ignoreFields)
+		for t, fields := range c.ignoreFields {
+			c.ignoreFields[t] = slices.Clip(fields)
+		}
+	}
+	c.printers = maps.Clone(f.printers)
+	c.ifacePrinters = slices.Clip(f.ifacePrinters)
+	c.transforms = maps.Clone(f.transforms)
+	c.noMethods = maps.Clone(f.noMethods)
+	c.ignoreTypes = maps.Clone(f.ignoreTypes)
+	c.ignorePaths = slices.Clip(f.ignorePaths)
+	c.onlyPaths = slices.Clip(f.onlyPaths)
+	if f.redactFields != nil {
+		c.redactFields = maps.Clone(f.redactFields)
+		for t, fields := range c.redactFields {
+			c.redactFields[t] = slices.Clip(fields)
+		}
+	}
+	c.redactTypes = maps.Clone(f.redactTypes)
+	if f.zeroFields != nil {
+		c.zeroFields = maps.Clone(f.zeroFields)
+		for t, fields := range c.zeroFields {
+			c.zeroFields[t] = slices.Clip(fields)
+		}
+	}
+	c.zeroTypes = maps.Clone(f.zeroTypes)
+	c.typeElems = maps.Clone(f.typeElems)
+	c.kindElems = maps.Clone(f.kindElems)
+	c.typeDepths = maps.Clone(f.typeDepths)
+	c.sortElems = maps.Clone(f.sortElems)
+	c.fieldFilters = slices.Clip(f.fieldFilters)
+	c.fieldPatterns = slices.Clip(f.fieldPatterns)
+	// The plans depend on FieldOrder, which the caller may change.
+	c.plans = nil
+	return &c
+}
+
+// With returns a copy of f configured with opts, for a single call
+// that needs different settings, as in
+//
+//	f.With(WithMaxDepth(20), WithShowZero(true)).Print(x)
+//
+// f is unchanged.
+func (f *Formatter) With(opts ...Option) *Formatter {
+	c := f.Clone()
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// SprintWith is like [Formatter.Sprint] of x, but with opts applied
+// for this call only. See [Formatter.With].
+func (f *Formatter) SprintWith(x any, opts ...Option) string {
+	return f.With(opts...).Sprint(x)
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "testing"
+
+func TestOptions(t *testing.T) {
+	preset := Options(WithCompact(true), WithOmitPackage(true))
+	f := New(preset, WithIgnoreFields(node{}, "Next"))
+	in := &node{I: 1, Next: &node{I: 2}}
+	if got, want := f.Sprint(in), "&node{I: 1}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	c := f.Clone().IgnoreFields(node{}, "I")
+	c.ShowZero = true
+	if got, want := c.Sprint(in), "&node{}"; got != want {
+		t.Errorf("clone: got %q, want %q", got, want)
+	}
+	// The original is unchanged.
+	if got, want := f.Sprint(in), "&node{I: 1}"; got != want {
+		t.Errorf("after clone: got %q, want %q", got, want)
+	}
+}
+
+func TestWith(t *testing.T) {
+	f := New(WithCompact(true), WithOmitPackage(true), WithMaxDepth(2))
+	in := &node{I: 1, Next: &node{I: 2, Next: &node{I: 3}}}
+	if got, want := f.SprintWith(in, WithMaxDepth(10), WithShowZero(true)),
+		"&node{I: 1, Next: &node{I: 2, Next: &node{I: 3, Next: &nil}}}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// The original is unchanged.
+	if got, want := f.Sprint(in), "&node{I: 1, Next: &node{I: 2, Next: &node{<maxdepth>: <maxdepth>}}}"; got != want {
+		t.Errorf("after With: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"reflect"
+	"sync"
+)
+
+// An orderedMapKind describes how to iterate over an ordered map:
+// a defined struct type that keeps the entries of a map in an order
+// of its own, like the order of insertion.
+type orderedMapKind int
+
+const (
+	notOrderedMap orderedMapKind = iota
+	keysMap                      // methods Keys() []K and Get(K) (V, bool)
+	oldestMap                    // method Oldest() *P, where P has fields Key and Value and a method Next() *P
+)
+
+// orderedMapKinds caches the results of orderedMapKindOf.
+var orderedMapKinds sync.Map // reflect.Type -> orderedMapKind
+
+// orderedMapKindOf returns the kind of ordered map that t, a struct type, is.
+// The methods may have pointer receivers.
+func orderedMapKindOf(t reflect.Type) orderedMapKind {
+	if k, ok := orderedMapKinds.Load(t); ok {
+		return k.(orderedMapKind)
+	}
+	k := notOrderedMap
+	pt := reflect.PointerTo(t)
+	if keys, ok := pt.MethodByName("Keys"); ok {
+		get, ok := pt.MethodByName("Get")
+		kt, gt := keys.Type, get.Type
+		if ok && kt.NumIn() == 1 && kt.NumOut() == 1 && kt.Out(0).Kind() == reflect.Slice &&
+			gt.NumIn() == 2 && gt.In(1) == kt.Out(0).Elem() && gt.NumOut() == 2 && gt.Out(1) == boolType {
+			k = keysMap
+		}
+	} else if oldest, ok := pt.MethodByName("Oldest"); ok {
+		ot := oldest.Type
+		if ot.NumIn() == 1 && ot.NumOut() == 1 && isPair(ot.Out(0)) {
+			k = oldestMap
+		}
+	}
+	orderedMapKinds.Store(t, k)
+	return k
+}
+
+// isPair reports whether t is a pointer to an entry of an oldestMap.
+func isPair(t reflect.Type) bool {
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	for _, name := range []string{"Key", "Value"} {
+		if f, ok := t.Elem().FieldByName(name); !ok || !f.IsExported() {
+			return false
+		}
+	}
+	next, ok := t.MethodByName("Next")
+	return ok && next.Type.NumIn() == 1 && next.Type.NumOut() == 1 && next.Type.Out(0) == t
+}
+
+// printOrderedMap prints an ordered map like a map of the same type,
+// with its entries in its own order:
+//
+//	orderedmap.OrderedMap{"b": 1, "a": 2}
+//
+// It reports whether v is an ordered map.
+func (s *state) printOrderedMap(v reflect.Value) bool {
+	if !s.OrderedMaps || s.GoSyntax || v.Kind() != reflect.Struct || !v.CanInterface() || v.Type().PkgPath() == "" {
+		return false
+	}
+	kind := orderedMapKindOf(v.Type())
+	if kind == notOrderedMap {
+		return false
+	}
+	limit := s.maxElements(v.Type())
+	n := -1
+	if limit > 0 && s.TailElements <= 0 {
+		// Get one more than the limit, to show that there are more.
+		n = limit + 1
+	}
+	var es []mapEntry
+	if err := catch(func() { es = orderedEntries(addressOf(v), kind, n) }); err != nil {
+		s.printPanic(err)
+		return true
+	}
+	s.prType(v.Type())
+	s.prc(punctClass, "{")
+	s.printEntries(es, limit, s.TailElements)
+	return true
+}
+
+// orderedEntries returns the entries of the ordered map that p points to,
+// or its first n entries if n >= 0.
+// It stops walking the entries of an oldestMap when they form a cycle.
+func orderedEntries(p reflect.Value, kind orderedMapKind, n int) []mapEntry {
+	var es []mapEntry
+	switch kind {
+	case keysMap:
+		keys := p.MethodByName("Keys").Call(nil)[0]
+		get := p.MethodByName("Get")
+		for i := range keys.Len() {
+			if len(es) == n {
+				break
+			}
+			k := keys.Index(i)
+			es = append(es, mapEntry{k, get.Call([]reflect.Value{k})[0]})
+		}
+	case oldestMap:
+		seen := map[uintptr]bool{}
+		for e := p.MethodByName("Oldest").Call(nil)[0]; !e.IsNil() && len(es) != n && !seen[e.Pointer()]; e = e.MethodByName("Next").Call(nil)[0] {
+			seen[e.Pointer()] = true
+			es = append(es, mapEntry{e.Elem().FieldByName("Key"), e.Elem().FieldByName("Value")})
+		}
+	}
+	return es
+}
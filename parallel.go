@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"maps"
+	"reflect"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// parallel reports whether to print the elements of v, a slice or array,
+// with printParallel. That requires v to be the top-level value, with
+// each element on its own lines, and nothing that depends on the output
+// of earlier elements: a budget, a limit on the number of elements,
+// or pointer labels.
+func (s *state) parallel(v reflect.Value) bool {
+	if s.Parallel <= 0 || v.Len() < s.Parallel || s.depth != 0 || s.compact || s.discard {
+		return false
+	}
+	if limit := s.maxElements(v.Type()); limit > 0 && v.Len() > limit {
+		return false
+	}
+	return s.maxBytes == 0 && s.maxLines == 0 && s.shared == nil &&
+		!s.PointerLabels && !s.Deterministic
+}
+
+// printParallel prints count elements, after the opening brace, as
+// printElements does. It divides them into chunks, one for each of up to
+// GOMAXPROCS goroutines, and prints each chunk into a separate buffer.
+// index must be safe to call concurrently.
+// A panic in a goroutine is repanicked in the caller's goroutine,
+// so it can be recovered as if the elements were printed serially.
+func (s *state) printParallel(count int, index func(int) reflect.Value) {
+	s.pr("\n")
+	size := (count + runtime.GOMAXPROCS(0) - 1) / runtime.GOMAXPROCS(0)
+	var chunks []*state
+	var wg sync.WaitGroup
+	panics := make([]any, (count+size-1)/size) // recovered in each goroutine
+	for start := 0; start < count; start += size {
+		c := s.chunk()
+		chunks = append(chunks, c)
+		wg.Add(1)
+		go func(n, end int) {
+			defer wg.Done()
+			defer func() { panics[n] = recover() }()
+			for i := start; i < end; i++ {
+				if !c.enterIndex(i) {
+					continue
+				}
+				line := c.lines
+				c.print(index(i))
+				c.afterElement(line)
+				c.leave()
+			}
+		}(len(chunks)-1, min(start+size, count))
+	}
+	wg.Wait()
+	for _, p := range panics {
+		if p != nil {
+			panic(p)
+		}
+	}
+	for _, c := range chunks {
+		if s.err != nil {
+			return
+		}
+		s.buf = append(s.buf, c.buf...)
+		s.nbytes += c.nbytes
+		s.lines += c.lines
+		s.problems = append(s.problems, c.problems...)
+		if s.out != nil && len(s.buf) >= s.ChunkSize {
+			s.flush()
+		}
+	}
+	s.prc(punctClass, "}")
+}
+
+// chunk returns a copy of s for printing a chunk of elements
+// with printParallel, at the start of a line.
+func (s *state) chunk() *state {
+	c := *s
+	c.buf = nil
+	c.out = nil
+	c.col = 0
+	c.nbytes = 0
+	c.lines = 0
+	c.problems = nil
+	c.glued = nil
+	c.scratch = nil
+	c.indents = nil
+	c.path = slices.Clone(s.path)
+	c.seen = maps.Clone(s.seen)
+	c.transforming = maps.Clone(s.transforming)
+	c.depthTypes = maps.Clone(s.depthTypes)
+	c.pulled = maps.Clone(s.pulled)
+	return &c
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A Path is the sequence of steps from the top-level value to a value
+// within it. Each step is a field name, an index like "[3]", or a map key
+// like `["a"]`. Pointers and interfaces add no steps.
+//
+// A Path is written by joining its steps, with a "." before each field name
+// except at the start, as in
+//
+//	Users[3].Password
+//
+// In a path pattern, a "*" step matches any field name,
+// and "[*]" matches any index or map key.
+type Path []string
+
+// String returns p in the form used by IgnorePaths and OnlyPaths.
+func (p Path) String() string {
+	var b strings.Builder
+	for i, step := range p {
+		if i > 0 && !strings.HasPrefix(step, "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(step)
+	}
+	return b.String()
+}
+
+// parsePath parses a path pattern.
+func parsePath(s string) (Path, error) {
+	var p Path
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+		case '[':
+			n, err := bracketLen(s)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, s[:n])
+			s = s[n:]
+		default:
+			n := strings.IndexAny(s, ".[")
+			if n < 0 {
+				n = len(s)
+			}
+			p = append(p, s[:n])
+			s = s[n:]
+		}
+	}
+	return p, nil
+}
+
+// bracketLen returns the length of the bracketed step at the start of s,
+// allowing for a quoted string inside the brackets.
+func bracketLen(s string) (int, error) {
+	i := 1
+	if i < len(s) && s[i] == '"' {
+		q, err := strconv.QuotedPrefix(s[i:])
+		if err != nil {
+			return 0, fmt.Errorf("bad quoted key in path %q", s)
+		}
+		i += len(q)
+	}
+	j := strings.IndexByte(s[i:], ']')
+	if j < 0 {
+		return 0, fmt.Errorf("missing ']' in path %q", s)
+	}
+	return i + j + 1, nil
+}
+
+func mustParsePaths(ss []string) []Path {
+	var ps []Path
+	for _, s := range ss {
+		p, err := parsePath(s)
+		if err != nil {
+			panic(err)
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// matchPrefix reports whether pattern matches the first len(pattern) steps of p.
+func matchPrefix(pattern, p Path) bool {
+	if len(pattern) > len(p) {
+		return false
+	}
+	for i, pat := range pattern {
+		if !matchStep(pat, p[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchStep reports whether a step of a pattern matches a step of a path.
+func matchStep(pat, step string) bool {
+	switch {
+	case pat == step:
+		return true
+	case pat == "*":
+		return !strings.HasPrefix(step, "[")
+	case pat == "[*]":
+		return strings.HasPrefix(step, "[")
+	default:
+		return false
+	}
+}
+
+// IgnorePaths causes f to skip printing of the values at the given paths.
+// Paths are written as field names, indexes and map keys,
+// like "Config.Secrets", `Users[3].Name` or `Limits["max"]`,
+// where "*" matches any field name and "[*]" any index or key.
+// The top-level value has the empty path.
+// IgnorePaths panics if a path is malformed.
+// It returns its receiver.
+func (f *Formatter) IgnorePaths(paths ...string) *Formatter {
+	f.ignorePaths = append(f.ignorePaths, mustParsePaths(paths)...)
+	return f
+}
+
+// OnlyPaths causes f to print only the values at the given paths,
+// the values contained in them, and the values needed to reach them.
+// Paths are written as for [Formatter.IgnorePaths].
+// OnlyPaths panics if a path is malformed.
+// It returns its receiver.
+func (f *Formatter) OnlyPaths(paths ...string) *Formatter {
+	f.onlyPaths = append(f.onlyPaths, mustParsePaths(paths)...)
+	return f
+}
+
+// tracksPaths reports whether the path to each value is needed,
+// to select values, to report problems with Strict or for PathComments.
+func (f *Formatter) tracksPaths() bool {
+	return len(f.ignorePaths) > 0 || len(f.onlyPaths) > 0 || f.Strict || f.PathComments
+}
+
+// pathSelected reports whether the value at p should be printed,
+// according to IgnorePaths and OnlyPaths.
+func (f *Formatter) pathSelected(p Path) bool {
+	for _, pat := range f.ignorePaths {
+		if matchPrefix(pat, p) {
+			return false
+		}
+	}
+	if len(f.onlyPaths) == 0 {
+		return true
+	}
+	for _, pat := range f.onlyPaths {
+		// p is within pat, or on the way to it.
+		if matchPrefix(pat, p) || matchPrefix(p, pat) {
+			return true
+		}
+	}
+	return false
+}
+
+// enter adds step to the current path if paths are being tracked.
+// It reports whether the value at the new path should be printed;
+// if not, the path is left unchanged.
+// If enter returns true, the caller must call leave.
+func (s *state) enter(step func() string) bool {
+	if !s.tracksPaths() {
+		return true
+	}
+	s.path = append(s.path, step())
+	if !s.pathSelected(s.path) {
+		s.path = s.path[:len(s.path)-1]
+		return false
+	}
+	return true
+}
+
+func (s *state) leave() {
+	if s.tracksPaths() {
+		s.path = s.path[:len(s.path)-1]
+	}
+}
+
+func (s *state) enterField(name string) bool {
+	return s.enter(func() string { return name })
+}
+
+func (s *state) enterIndex(i int) bool {
+	return s.enter(func() string { return "[" + strconv.Itoa(i) + "]" })
+}
+
+func (s *state) enterKey(key reflect.Value) bool {
+	return s.enter(func() string { return keyStep(s.Formatter, key) })
+}
+
+func keyStep(f *Formatter, key reflect.Value) string {
+	return "[" + f.sprintCompact(key) + "]"
+}
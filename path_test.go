@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want Path
+	}{
+		{"", nil},
+		{"A", Path{"A"}},
+		{"A.B", Path{"A", "B"}},
+		{"Users[*].Password", Path{"Users", "[*]", "Password"}},
+		{`M["a.b]"][2]`, Path{"M", `["a.b]"]`, "[2]"}},
+		{"[0].X", Path{"[0]", "X"}},
+	} {
+		got, err := parsePath(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("parsePath(%q) = %q, want %q", test.in, got, test.want)
+		}
+		if got.String() != test.in {
+			t.Errorf("%q.String() = %q, want %q", got, got.String(), test.in)
+		}
+	}
+
+	for _, in := range []string{"A[", `A["x]`} {
+		if _, err := parsePath(in); err == nil {
+			t.Errorf("parsePath(%q): got nil error, want error", in)
+		}
+	}
+}
+
+func TestMatchPrefix(t *testing.T) {
+	for _, test := range []struct {
+		pattern, p string
+		want       bool
+	}{
+		{"", "A", true},
+		{"A", "A", true},
+		{"A", "A.B", true},
+		{"A.B", "A", false},
+		{"A[*].B", "A[3].B", true},
+		{"A[*].B", "A.C.B", false},
+		{"*.B", "A.B", true},
+		{"*.B", "[0].B", false},
+	} {
+		got := matchPrefix(mustParsePaths([]string{test.pattern})[0], mustParsePaths([]string{test.p})[0])
+		if got != test.want {
+			t.Errorf("matchPrefix(%q, %q) = %t, want %t", test.pattern, test.p, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// A fieldPlan holds what the formatter needs to know about a struct field,
+// computed once per type.
+type fieldPlan struct {
+	index    int
+	field    reflect.StructField
+	exported bool
+	tag      tagOptions
+	json     jsonTag
+}
+
+// structPlans caches the results of structPlan.
+var structPlans sync.Map // reflect.Type -> []fieldPlan
+
+// structPlan returns the fieldPlans for struct type t, in declaration order.
+// Fields omitted by their struct tags are not included.
+func structPlan(t reflect.Type) []fieldPlan {
+	if p, ok := structPlans.Load(t); ok {
+		return p.([]fieldPlan)
+	}
+	var plan []fieldPlan
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := parseTag(sf)
+		if tag.omit {
+			continue
+		}
+		plan = append(plan, fieldPlan{
+			index:    i,
+			field:    sf,
+			exported: sf.IsExported(),
+			tag:      tag,
+			json:     parseJSONTag(sf),
+		})
+	}
+	p, _ := structPlans.LoadOrStore(t, plan)
+	return p.([]fieldPlan)
+}
+
+// exportedFields returns the number of exported fields of struct type t
+// that are not omitted by their struct tags: the fields that may be printed.
+func exportedFields(t reflect.Type) int {
+	n := 0
+	for _, fp := range structPlan(t) {
+		if fp.exported {
+			n++
+		}
+	}
+	return n
+}
+
+// fieldPlans returns the fieldPlans for struct type t, in the order
+// given by FieldOrder.
+func (f *Formatter) fieldPlans(t reflect.Type) []fieldPlan {
+	if plan, ok := f.plans[t]; ok {
+		return plan
+	}
+	plan := structPlan(t)
+	if f.FieldOrder == nil {
+		return plan
+	}
+	return slices.SortedStableFunc(slices.Values(plan), func(a, b fieldPlan) int {
+		return f.FieldOrder(a.field, b.field)
+	})
+}
+
+// FieldsByName orders struct fields alphabetically by name.
+// Use it as a Formatter's FieldOrder.
+func FieldsByName(a, b reflect.StructField) int {
+	return strings.Compare(a.Name, b.Name)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+// Package protoformat connects the format package to
+// [google.golang.org/protobuf].
+// Its [Option] makes a [format.Formatter] print the structs generated
+// for protocol buffer messages by their proto field names, like
+//
+//	&pb.Field{kind: TYPE_STRING, json_name: "x"}
+//
+// The format package itself does not depend on protocol buffers.
+package protoformat
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jba/format"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Option returns a [format.Option] that configures a Formatter to print
+// protocol buffer messages readably:
+//   - Fields of generated message structs are named by their proto names,
+//     like type_url instead of TypeUrl.
+//   - Fields that hold internal state, like the XXX_ fields of older
+//     generated code, are omitted. Unexported fields, like state and
+//     sizeCache, are omitted by every Formatter.
+//   - Enum values print as their names, like TYPE_STRING.
+//
+// Fields of other structs are named by the Formatter's existing
+// FieldNameFunc, if any.
+func Option() format.Option {
+	return func(f *format.Formatter) {
+		next := f.FieldNameFunc
+		f.FieldNameFunc = func(sf reflect.StructField) string {
+			if name, ok := fieldName(sf); ok {
+				return name
+			}
+			if next != nil {
+				return next(sf)
+			}
+			return sf.Name
+		}
+		format.FormatInterface(f, enumString)
+	}
+}
+
+// fieldName returns the name to print for sf, if it belongs to a
+// generated message struct, and reports whether it does.
+// The name is empty for fields that should not be printed.
+func fieldName(sf reflect.StructField) (string, bool) {
+	if strings.HasPrefix(sf.Name, "XXX_") {
+		return "", true
+	}
+	if name, ok := sf.Tag.Lookup("protobuf_oneof"); ok {
+		return name, true
+	}
+	tag, ok := sf.Tag.Lookup("protobuf")
+	if !ok {
+		return "", false
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		if name, ok := strings.CutPrefix(opt, "name="); ok {
+			return name, true
+		}
+	}
+	return sf.Name, true
+}
+
+// enumString returns the name of e's value, or its number if it has none.
+func enumString(e protoreflect.Enum) string {
+	n := e.Number()
+	if v := e.Descriptor().Values().ByNumber(n); v != nil {
+		return string(v.Name())
+	}
+	return strconv.Itoa(int(n))
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package protoformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jba/format"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestOption(t *testing.T) {
+	f := format.New(Option(), format.WithCompact(true), format.WithOmitPackage(true))
+	for _, test := range []struct {
+		in   any
+		want string
+	}{
+		{
+			&typepb.Field{Kind: typepb.Field_TYPE_STRING, Name: "f", TypeUrl: "u", JsonName: "j"},
+			`&Field{kind: TYPE_STRING, name: "f", type_url: "u", json_name: "j"}`,
+		},
+		{typepb.Field_Kind(99), "99"},
+		{
+			&structpb.Value{Kind: &structpb.Value_StringValue{StringValue: "s"}},
+			`&Value{kind: &Value_StringValue{string_value: "s"}}`,
+		},
+		{struct{ TypeUrl string }{"u"}, `struct { TypeUrl string }{TypeUrl: "u"}`},
+	} {
+		if got := f.Sprint(test.in); got != test.want {
+			t.Errorf("got  %s\nwant %s", got, test.want)
+		}
+	}
+}
+
+func TestOptionComposes(t *testing.T) {
+	f := format.New(
+		format.WithFieldNameFunc(func(sf reflect.StructField) string { return strings.ToLower(sf.Name) }),
+		Option(),
+		format.WithCompact(true), format.WithOmitPackage(true))
+	in := struct {
+		A string
+		F *typepb.Field
+	}{"a", &typepb.Field{Number: 1}}
+	want := `struct { A string; F *Field }{a: "a", f: &Field{number: 1}}`
+	if got := f.Sprint(in); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
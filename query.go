@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// SprintPath calls [Formatter.SprintPath] with the default Formatter.
+func SprintPath(x any, pattern string) string { return New().SprintPath(x, pattern) }
+
+// SprintPath formats only the values within x at paths that match pattern,
+// each after its path, as in
+//
+//	Orders[2].Items[0].SKU: "A-100"
+//	Orders[2].Items[1].SKU: "B-200"
+//
+// The pattern is written as for [Formatter.IgnorePaths].
+// Pointers and interfaces are followed to reach the values, and
+// MaxElements does not limit the elements searched.
+// SprintPath returns the empty string if no values match.
+// It panics if the pattern is malformed.
+func (f *Formatter) SprintPath(x any, pattern string) string {
+	pat := mustParsePaths([]string{pattern})[0]
+	var b []byte
+	f.find(reflect.ValueOf(x), nil, pat, func(p Path, v reflect.Value) {
+		if len(b) > 0 && b[len(b)-1] != '\n' {
+			b = append(b, '\n')
+		}
+		if len(p) > 0 {
+			b = append(b, p.String()+": "...)
+		}
+		b = f.appendBody(b, v, nil)
+	})
+	return string(b)
+}
+
+// find calls fn on each value within v, which is at p, whose path
+// continues with steps matching pattern.
+func (f *Formatter) find(v reflect.Value, p, pattern Path, fn func(Path, reflect.Value)) {
+	if len(pattern) == 0 {
+		fn(slices.Clip(p), v)
+		return
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	pat, rest := pattern[0], pattern[1:]
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, fp := range f.fieldPlans(v.Type()) {
+			if name := fp.field.Name; fp.exported && matchStep(pat, name) {
+				f.find(v.Field(fp.index), append(p, name), rest, fn)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if step := "[" + strconv.Itoa(i) + "]"; matchStep(pat, step) {
+				f.find(v.Index(i), append(p, step), rest, fn)
+			}
+		}
+	case reflect.Map:
+		// If sorting panics, the entries are in no particular order.
+		es, _ := mapEntries(v)
+		es, _ = f.orderEntries(es)
+		for _, e := range es {
+			if step := keyStep(f, e.key); matchStep(pat, step) {
+				f.find(e.val, append(p, step), rest, fn)
+			}
+		}
+	}
+}
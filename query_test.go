@@ -0,0 +1,33 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "testing"
+
+func TestSprintPath(t *testing.T) {
+	in := &team{
+		Players: []Player{{Name: "Al", Score: 1}, {Name: "Barbara", Score: 2}},
+		M:       map[string]int{"a": 1, "b": 2},
+	}
+	f := New(WithOmitPackage(true), WithMaxElements(1))
+	for _, test := range []struct {
+		pattern string
+		want    string
+	}{
+		{"Players[*].Name", "Players[0].Name: \"Al\"\nPlayers[1].Name: \"Barbara\"\n"},
+		{`M["b"]`, `M["b"]: 2` + "\n"},
+		{"Players[1]", "Players[1]: Player{\n    Name: \"Barbara\"\n    Score: 2\n}\n"},
+		{"Players[2].Name", ""},
+		{"M.X", ""},
+	} {
+		if got := f.SprintPath(in, test.pattern); got != test.want {
+			t.Errorf("%s:\ngot\n%s\nwant\n%s", test.pattern, got, test.want)
+		}
+	}
+
+	f.Compact = true
+	if got, want := f.SprintPath(in, "*"), "Players: []{Player{Name: \"Al\", Score: 1}, ...}\nM: {\"a\": 1, ...}"; got != want {
+		t.Errorf("compact:\ngot\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "reflect"
+
+var (
+	reflectTypeType  = reflect.TypeFor[reflect.Type]()
+	reflectValueType = reflect.TypeFor[reflect.Value]()
+)
+
+// reflectTypeString returns the name of the type in v, if v is a
+// non-nil reflect.Type.
+func (f *Formatter) reflectTypeString(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Interface || !v.CanInterface() || !v.Type().Implements(reflectTypeType) ||
+		(v.Kind() == reflect.Pointer && v.IsNil()) {
+		return "", false
+	}
+	return f.typeName(v.Interface().(reflect.Type)), true
+}
+
+// printReflectValue prints v, if it is a reflect.Value, as the value
+// it holds, annotated with its kind:
+//
+//	reflect.Value(kind=int, 3)
+//
+// It reports whether v is a reflect.Value.
+func (s *state) printReflectValue(v reflect.Value) bool {
+	if v.Type() != reflectValueType || !v.CanInterface() {
+		return false
+	}
+	rv := v.Interface().(reflect.Value)
+	s.prType(reflectValueType)
+	s.prc(punctClass, "(")
+	if !rv.IsValid() {
+		s.prc(markerClass, "invalid")
+	} else {
+		s.prc(markerClass, "kind="+rv.Kind().String())
+		// A value reached through an unexported field can't be printed.
+		if rv.CanInterface() {
+			s.prc(punctClass, ", ")
+			s.print(rv)
+		}
+	}
+	s.prc(punctClass, ")")
+	return true
+}
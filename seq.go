@@ -0,0 +1,94 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "reflect"
+
+var boolType = reflect.TypeFor[bool]()
+
+// seqArity returns 1 if t is the type of an iterator function like
+// iter.Seq, 2 if it is like iter.Seq2, and 0 otherwise.
+func seqArity(t reflect.Type) int {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return 0
+	}
+	y := t.In(0)
+	if y.Kind() != reflect.Func || y.NumOut() != 1 || y.Out(0) != boolType {
+		return 0
+	}
+	if n := y.NumIn(); n == 1 || n == 2 {
+		return n
+	}
+	return 0
+}
+
+// maxPulled is the number of values pulled from an iterator when
+// there is no limit on the number of elements, so an endless
+// iterator can still be printed.
+const maxPulled = 1000
+
+// A pulledSeq holds the values pulled from an iterator.
+type pulledSeq struct {
+	es  []mapEntry
+	err error // from a panic in the iterator
+}
+
+// printSeq prints the values yielded by v, a non-nil iterator function,
+// like a slice or map marked seq:
+//
+//	seq{1, 2, 3}
+//	seq{"a": 1, "b": 2}
+//
+// It stops the iterator after MaxElements values, the limit set with
+// MaxElementsFor, or maxPulled values if there is no limit.
+// The iterator is called only once, even if its values are measured
+// to see whether they fit on a line with Smart.
+// It reports whether v is an iterator function.
+func (s *state) printSeq(v reflect.Value) bool {
+	n := seqArity(v.Type())
+	if n == 0 {
+		return false
+	}
+	limit := s.maxElements(v.Type())
+	if limit <= 0 {
+		limit = maxPulled
+	}
+	p, ok := s.pulled[v]
+	if !ok {
+		p = pull(v, n, limit)
+		s.pulled[v] = p
+	}
+	if p.err != nil {
+		s.printPanic(p.err)
+		return true
+	}
+	es := p.es
+	s.prc(typeClass, "seq")
+	s.prc(punctClass, "{")
+	if n == 1 {
+		s.printElements(len(es), limit, 0, func(i int) reflect.Value { return es[i].key })
+	} else {
+		s.printEntries(es, limit, 0)
+	}
+	return true
+}
+
+// pull calls v, an iterator function yielding n values at a time,
+// and returns up to limit+1 of the values it yields, one more than
+// the limit to show that there are more.
+func pull(v reflect.Value, n, limit int) pulledSeq {
+	var es []mapEntry
+	yield := reflect.MakeFunc(v.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		if len(es) <= limit {
+			e := mapEntry{key: args[0]}
+			if n == 2 {
+				e.val = args[1]
+			}
+			es = append(es, e)
+		}
+		return []reflect.Value{reflect.ValueOf(len(es) <= limit)}
+	})
+	err := catch(func() { v.Call([]reflect.Value{yield}) })
+	return pulledSeq{es, err}
+}
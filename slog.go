@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+)
+
+// Slog calls [Formatter.Slog] with the default Formatter.
+func Slog(x any) slog.LogValuer { return New().Slog(x) }
+
+// Slog returns a [slog.LogValuer] whose value is x formatted by f
+// on a single line.
+// Use it to log values whose default slog representation is unhelpful:
+//
+//	logger.Info("loaded", "config", f.Slog(cfg))
+func (f *Formatter) Slog(x any) slog.LogValuer { return logValuer{f, x} }
+
+type logValuer struct {
+	f *Formatter
+	x any
+}
+
+func (l logValuer) LogValue() slog.Value {
+	return slog.StringValue(l.f.sprintLine(reflect.ValueOf(l.x)))
+}
+
+// NewHandler returns a [slog.Handler] that formats attribute values of
+// kind [slog.KindAny] with f, and passes the result to h.
+// Values that implement error are passed unchanged, so that handlers
+// can treat them specially.
+// If f is nil, the default Formatter is used.
+func NewHandler(h slog.Handler, f *Formatter) slog.Handler {
+	if f == nil {
+		f = New()
+	}
+	return &handler{h, f}
+}
+
+type handler struct {
+	h slog.Handler
+	f *Formatter
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	r2 := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		r2.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.h.Handle(ctx, r2)
+}
+
+func (h *handler) WithAttrs(as []slog.Attr) slog.Handler {
+	as2 := make([]slog.Attr, len(as))
+	for i, a := range as {
+		as2[i] = h.attr(a)
+	}
+	return &handler{h.h.WithAttrs(as2), h.f}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{h.h.WithGroup(name), h.f}
+}
+
+// attr formats the value of a, and of every attribute in a group.
+func (h *handler) attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindAny:
+		x := v.Any()
+		if _, ok := x.(error); ok {
+			return slog.Attr{Key: a.Key, Value: v}
+		}
+		return slog.String(a.Key, h.f.sprintLine(reflect.ValueOf(x)))
+	case slog.KindGroup:
+		as := v.Group()
+		as2 := make([]slog.Attr, len(as))
+		for i, ga := range as {
+			as2[i] = h.attr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(as2...)}
+	default:
+		return slog.Attr{Key: a.Key, Value: v}
+	}
+}
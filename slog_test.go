@@ -0,0 +1,38 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlog(t *testing.T) {
+	f := New(WithOmitPackage(true))
+	removeTime := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{}
+		}
+		return a
+	}
+	p := Player{Name: "Al", Score: 1}
+
+	var buf strings.Builder
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	l.Info("m", "p", f.Slog(p))
+	if got, want := buf.String(), `level=INFO msg=m p="Player{Name: \"Al\", Score: 1}"`+"\n"; got != want {
+		t.Errorf("Slog:\ngot  %s\nwant %s", got, want)
+	}
+
+	buf.Reset()
+	h := NewHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: removeTime}), f)
+	l = slog.New(h).With("s", []int{1})
+	l.Info("m", "n", 1, "err", errors.New("e"), slog.Group("g", "p", &p))
+	want := `level=INFO msg=m s=[]{1} n=1 err=e g.p="&Player{Name: \"Al\", Score: 1}"` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Handler:\ngot  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// SortSlices causes f to print the elements of some slices and arrays in
+// sorted order, for values whose order doesn't matter, like results
+// gathered concurrently. Diff also ignores their order.
+// Each sorter is either a function of the form func(T, T) bool that
+// reports whether one element is less than another, as in
+// cmpopts.SortSlices, or a slice or array of T, whose elements are
+// then sorted in the order used for map keys. Either way, it applies to
+// all slices and arrays of T. Elements that compare equal keep their order.
+// The paths of the elements, as in IgnorePaths, are their positions after
+// sorting.
+// It returns f.
+func (f *Formatter) SortSlices(sorters ...any) *Formatter {
+	if f.sortElems == nil {
+		f.sortElems = map[reflect.Type]func(a, b reflect.Value) int{}
+	}
+	for _, s := range sorters {
+		t := reflect.TypeOf(s)
+		switch {
+		case t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+			f.sortElems[t.Elem()] = compareValues
+		case t != nil && t.Kind() == reflect.Func && t.NumIn() == 2 && t.In(0) == t.In(1) &&
+			t.NumOut() == 1 && t.Out(0).Kind() == reflect.Bool:
+			less := reflect.ValueOf(s)
+			lt := func(a, b reflect.Value) bool {
+				return less.Call([]reflect.Value{a, b})[0].Bool()
+			}
+			f.sortElems[t.In(0)] = func(a, b reflect.Value) int {
+				switch {
+				case lt(a, b):
+					return -1
+				case lt(b, a):
+					return 1
+				default:
+					return 0
+				}
+			}
+		default:
+			panic(fmt.Sprintf("SortSlices: %T is not a less function or a slice or array", s))
+		}
+	}
+	return f
+}
+
+// sortedElements returns the elements of v, a slice or array, in the
+// order given by SortSlices, or nil if they aren't sorted.
+// If the less function panics, it returns the panic as err.
+func (f *Formatter) sortedElements(v reflect.Value) (elems []reflect.Value, err error) {
+	cmp := f.sortElems[v.Type().Elem()]
+	if cmp == nil {
+		return nil, nil
+	}
+	elems = make([]reflect.Value, v.Len())
+	for i := range elems {
+		elems[i] = v.Index(i)
+	}
+	err = catch(func() { slices.SortStableFunc(elems, cmp) })
+	return elems, err
+}
+
+// elementIndex returns a function that returns the elements of v,
+// a slice or array, in the order given by SortSlices.
+// If they can't be sorted, it uses their order in v.
+func (d *differ) elementIndex(v reflect.Value) func(int) reflect.Value {
+	if elems, err := d.sortedElements(v); err == nil && elems != nil {
+		return func(i int) reflect.Value { return elems[i] }
+	}
+	return v.Index
+}
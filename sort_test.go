@@ -0,0 +1,39 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "testing"
+
+func TestSortSlices(t *testing.T) {
+	f := New(WithCompact(true), WithOmitPackage(true),
+		WithSortSlices([]string(nil), func(a, b Player) bool { return a.Score > b.Score }))
+	for _, test := range []struct {
+		in   any
+		want string
+	}{
+		{[]string{"c", "a", "b"}, `[]{"a", "b", "c"}`},
+		{[2]string{"y", "x"}, `[2]{"x", "y"}`},
+		{[]int{3, 1, 2}, `[]{3, 1, 2}`},
+		{
+			[]Player{{Name: "a", Score: 1}, {Name: "b", Score: 3}, {Name: "c", Score: 1}},
+			`[]{Player{Name: "b", Score: 3}, Player{Name: "a", Score: 1}, Player{Name: "c", Score: 1}}`,
+		},
+	} {
+		if got := f.Sprint(test.in); got != test.want {
+			t.Errorf("%v: got %s, want %s", test.in, got, test.want)
+		}
+	}
+
+	if got := f.Diff([]string{"a", "b"}, []string{"b", "a"}); got != "" {
+		t.Errorf("Diff of reordered slices: got %q, want none", got)
+	}
+	if got, want := f.Diff([]string{"a", "b"}, []string{"c", "a"}), "[1]: got \"b\", want \"c\"\n"; got != want {
+		t.Errorf("Diff: got %q, want %q", got, want)
+	}
+
+	f.SortSlices(func(a, b int) bool { panic("bad") })
+	if got, want := f.Sprint([]int{2, 1}), "<panic: bad>"; got != want {
+		t.Errorf("panic: got %s, want %s", got, want)
+	}
+}
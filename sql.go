@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"database/sql"
+	"reflect"
+)
+
+var rawBytesType = reflect.TypeFor[sql.RawBytes]()
+
+// isSQLNull reports whether t is one of the nullable types of database/sql,
+// like sql.NullString or sql.Null[T], which hold a value and a Valid field.
+func isSQLNull(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == "database/sql" &&
+		t.NumField() == 2 && t.Field(1).Name == "Valid" && t.Field(1).Type.Kind() == reflect.Bool
+}
+
+// printSQL prints a nullable database/sql value as null if it is not
+// valid, and otherwise as the value it holds, marked with its type:
+//
+//	sql.NullString("x")
+//
+// It prints sql.RawBytes, which usually holds text, with BytesAuto unless
+// BytesMode is set.
+// It reports whether it printed v.
+func (s *state) printSQL(v reflect.Value) bool {
+	if s.GoSyntax {
+		return false
+	}
+	switch {
+	case isSQLNull(v.Type()):
+		if !v.Field(1).Bool() {
+			s.prc(keywordClass, "null")
+			return true
+		}
+		s.prType(v.Type())
+		s.prc(punctClass, "(")
+		s.print(v.Field(0))
+		s.prc(punctClass, ")")
+		return true
+	case v.Type() == rawBytesType && s.BytesMode == BytesList:
+		if v.IsNil() && s.printNil(v) {
+			return true
+		}
+		s.printBytes(v, BytesAuto)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+// stdStrings holds functions that format standard library types whose
+// fields are rarely useful. Each is passed a non-nil pointer to the value.
+// A function registered with FormatFunc for one of these types takes
+// precedence.
+var stdStrings = map[reflect.Type]func(reflect.Value) string{
+	reflect.TypeFor[big.Int]():          stringer,
+	reflect.TypeFor[big.Rat]():          stringer,
+	reflect.TypeFor[big.Float]():        bigFloatString,
+	reflect.TypeFor[netip.Addr]():       stringer,
+	reflect.TypeFor[netip.AddrPort]():   stringer,
+	reflect.TypeFor[netip.Prefix]():     stringer,
+	reflect.TypeFor[net.IP]():           stringer,
+	reflect.TypeFor[net.IPNet]():        stringer,
+	reflect.TypeFor[net.HardwareAddr](): stringer,
+	reflect.TypeFor[url.URL]():          urlString,
+	reflect.TypeFor[regexp.Regexp]():    regexpString,
+}
+
+func stringer(p reflect.Value) string {
+	return p.Interface().(fmt.Stringer).String()
+}
+
+// bigFloatString returns the shortest decimal that represents the
+// big.Float at its precision. Its String method rounds to 10 digits.
+func bigFloatString(p reflect.Value) string {
+	return p.Interface().(*big.Float).Text('g', -1)
+}
+
+// urlString formats a url.URL with its password, if any, redacted.
+func urlString(p reflect.Value) string {
+	return p.Interface().(*url.URL).Redacted()
+}
+
+// regexpString formats a regexp.Regexp as its quoted pattern.
+func regexpString(p reflect.Value) string {
+	return strconv.Quote(p.Interface().(*regexp.Regexp).String())
+}
+
+// stdString formats a value of one of the types in stdStrings, or a
+// non-nil pointer to one.
+func (f *Formatter) stdString(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.CanInterface() {
+		return "", false
+	}
+	fn := stdStrings[v.Type()]
+	if fn == nil || f.printers[v.Type()] != nil || (v.Kind() == reflect.Slice && v.IsNil()) {
+		return "", false
+	}
+	return fn(addressOf(v)), true
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors for values that were not printed in full. ErrPanic means that
+// one of the value's methods, or a function registered with the Formatter,
+// panicked. With Strict, Fprint and Print return a *StrictError that wraps them.
+var (
+	ErrMaxDepth    = errors.New("contents beyond MaxDepth")
+	ErrUnknownKind = errors.New("unknown reflect kind")
+	ErrPanic       = errors.New("panic")
+)
+
+// A StrictError is returned by [Formatter.Fprint] and [Formatter.Print]
+// when Strict is set and some values were not printed in full.
+// The output is written regardless, with markers in place of those values.
+type StrictError struct {
+	Problems []*Problem
+}
+
+func (e *StrictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "format: %d values not printed in full", len(e.Problems))
+	for i, p := range e.Problems {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(p.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the problems, so that errors.Is(err, ErrMaxDepth)
+// reports whether a value was beyond MaxDepth.
+func (e *StrictError) Unwrap() []error {
+	errs := make([]error, len(e.Problems))
+	for i, p := range e.Problems {
+		errs[i] = p
+	}
+	return errs
+}
+
+// A Problem is a value that was not printed in full.
+type Problem struct {
+	Path string // path to the value, in the syntax of IgnorePaths; "" for the value passed to Fprint
+	Err  error  // why the value wasn't printed, like ErrMaxDepth
+}
+
+func (p *Problem) Error() string {
+	if p.Path == "" {
+		return p.Err.Error()
+	}
+	return p.Path + ": " + p.Err.Error()
+}
+
+func (p *Problem) Unwrap() error { return p.Err }
+
+// problem records that the value at p wasn't printed in full because
+// of err, if f.Strict is set and s isn't just measuring.
+func (s *state) problem(p Path, err error) {
+	if !s.Strict || s.discard {
+		return
+	}
+	pr := &Problem{Path: p.String(), Err: err}
+	if n := len(s.problems); n > 0 && *s.problems[n-1] == *pr {
+		// Each field or element beyond MaxDepth reports its parent.
+		return
+	}
+	s.problems = append(s.problems, pr)
+}
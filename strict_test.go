@@ -0,0 +1,50 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	in := []*node{{I: 1}, {I: 2, Next: &node{I: 3, Next: &node{I: 4}}}}
+	f := New(WithCompact(true), WithOmitPackage(true), WithMaxDepth(3), WithStrict(true))
+	var b strings.Builder
+	err := f.Fprint(&b, in)
+	if got, want := b.String(), "[]{&node{I: 1}, &node{I: 2, Next: &node{I: 3, Next: &node{<maxdepth>: <maxdepth>}}}}"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	var se *StrictError
+	if !errors.As(err, &se) {
+		t.Fatalf("got %v, want a *StrictError", err)
+	}
+	if got, want := err.Error(), "format: 1 values not printed in full: [1].Next.Next: contents beyond MaxDepth"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Error("errors.Is(err, ErrMaxDepth) is false")
+	}
+
+	g := New(WithCompact(true), WithUseStringer(true), WithStrict(true))
+	err = g.Fprint(&b, map[string]panicker{"a": 1})
+	if got, want := err.Error(), "format: 1 values not printed in full: [\"a\"]: panic: boom"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !errors.Is(err, ErrPanic) {
+		t.Error("errors.Is(err, ErrPanic) is false")
+	}
+
+	// Without Strict, or with everything printed, there is no error.
+	f.MaxDepth = 10
+	if err := f.Fprint(&b, in); err != nil {
+		t.Errorf("deep enough: %v", err)
+	}
+	f.MaxDepth = 3
+	f.Strict = false
+	if err := f.Fprint(&b, in); err != nil {
+		t.Errorf("not strict: %v", err)
+	}
+}
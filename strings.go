@@ -0,0 +1,129 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A MultilineMode determines how a Formatter prints strings that contain
+// newlines. Compact output always uses MultilineQuote.
+type MultilineMode int
+
+const (
+	MultilineQuote MultilineMode = iota // as a quoted string, with \n escapes
+	MultilineRaw                        // as a raw string literal, if possible
+	MultilineBlock                      // as a block of lines, each after a |, between """ delimiters, if possible
+)
+
+// An EscapeMode determines how a Formatter prints strings that are not
+// valid UTF-8, or that contain non-printable characters.
+type EscapeMode int
+
+const (
+	EscapeQuote   EscapeMode = iota // with \xNN and \uNNNN escapes, as by strconv.Quote
+	EscapeReplace                   // with each invalid byte and non-printable character replaced by U+FFFD
+	EscapeHex                       // as bytes, like BytesHexDump; EscapeQuote with GoSyntax
+)
+
+// escapeString prints str according to the StringEscape mode, if that
+// mode changes how str is printed. It reports whether it printed str.
+func (s *state) escapeString(str string) bool {
+	if s.StringEscape != EscapeHex || s.GoSyntax || isText([]byte(str)) {
+		return false
+	}
+	s.printBytes(reflect.ValueOf([]byte(str)), BytesHexDump)
+	return true
+}
+
+// replaceUnprintable returns str with each invalid byte and each
+// non-printable character other than common whitespace replaced by U+FFFD.
+func replaceUnprintable(str string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' || r == '\r' || unicode.IsPrint(r) {
+			return r
+		}
+		return utf8.RuneError
+	}, str)
+}
+
+// printMultiline prints str, which contains a newline, according to
+// the Multiline mode. It reports whether it printed anything; if not,
+// str should be quoted.
+// A block has the form
+//
+//	"""
+//	    | line 1
+//	    | line 2"""
+//
+// with its lines indented one level deeper than the value, after a "|"
+// that shows where each line starts, even if it begins with spaces.
+// The closing delimiter is on a line of its own if str ends in a newline.
+// Blocks are not Go syntax; with GoSyntax, MultilineBlock is treated
+// as MultilineRaw.
+func (s *state) printMultiline(str string) bool {
+	if s.compact || !isRawText(str) {
+		return false
+	}
+	mode := s.Multiline
+	if mode == MultilineBlock && (s.GoSyntax || strings.Contains(str, `"""`)) {
+		mode = MultilineRaw
+	}
+	switch mode {
+	case MultilineRaw:
+		if strings.Contains(str, "`") {
+			return false
+		}
+		s.prUnbroken(stringClass, "`"+str+"`")
+	case MultilineBlock:
+		s.prUnbroken(punctClass, `"""`)
+		s.depth++
+		lines := strings.Split(str, "\n")
+		for i, line := range lines {
+			s.write("\n")
+			if line == "" && i == len(lines)-1 {
+				break // str ends in a newline
+			}
+			s.prUnbroken(punctClass, "|")
+			if line != "" {
+				s.writeClass(stringClass, " "+line)
+			}
+		}
+		s.prUnbroken(punctClass, `"""`)
+		s.depth--
+	default:
+		return false
+	}
+	return true
+}
+
+// prUnbroken is like prc, but never breaks the line to observe MaxWidth,
+// since that would change the meaning of str.
+// Any glued tokens are written first.
+func (s *state) prUnbroken(c class, str string) {
+	if s.err != nil {
+		return
+	}
+	s.startLine()
+	s.writeGlued()
+	s.writeClass(c, str)
+}
+
+// isRawText reports whether str can be written without escapes:
+// whether it is valid UTF-8 with no control characters other than
+// newlines and tabs.
+func isRawText(str string) bool {
+	if !utf8.ValidString(str) {
+		return false
+	}
+	for _, r := range str {
+		if r != '\n' && r != '\t' && !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
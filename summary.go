@@ -0,0 +1,122 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Summary describes x without printing its contents: its type, its
+// length if it has one, the number of exported fields of the struct it
+// holds, and how deeply its values are nested, as in
+//
+//	[]*Order len=12000 (Order has 14 fields, max depth 6)
+//
+// It is useful for deciding whether to print a large value in full.
+// The depth does not count pointers and interfaces, and does not exceed
+// MaxDepth.
+func (f *Formatter) Summary(x any) string {
+	v := reflect.ValueOf(x)
+	if !v.IsValid() {
+		return "nil"
+	}
+	var b strings.Builder
+	b.WriteString(f.typeName(v.Type()))
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan, reflect.String:
+		b.WriteString(" len=" + strconv.Itoa(v.Len()))
+	}
+	var stats []string
+	if st := structElem(v.Type()); st != nil {
+		stats = append(stats, fmt.Sprintf("%s has %d fields", f.typeName(st), exportedFields(st)))
+	}
+	d := &depthCounter{max: f.maxDepth(), seen: map[ptrKey]bool{}, done: map[ptrKey]depthAt{}}
+	stats = append(stats, "max depth "+strconv.Itoa(d.depth(v, 0)))
+	b.WriteString(" (" + strings.Join(stats, ", ") + ")")
+	return b.String()
+}
+
+// structElem returns the struct type that t holds, following pointers
+// and the elements of slices, arrays and maps, or nil if there is none.
+func structElem(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Struct:
+			return t
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return nil
+		}
+	}
+}
+
+// A depthCounter measures how deeply values are nested.
+type depthCounter struct {
+	max  int                // stop at this depth
+	seen map[ptrKey]bool    // pointers being visited, to detect cycles
+	done map[ptrKey]depthAt // pointers visited, so shared values are measured once
+}
+
+// A depthAt is the depth of a value reached at nesting level n.
+type depthAt struct{ n, depth int }
+
+// depth returns the number of levels of structs, slices, arrays and maps
+// in v, which is nested in n levels, up to d.max in all.
+func (d *depthCounter) depth(v reflect.Value, n int) int {
+	if n >= d.max {
+		return 0
+	}
+	deepest := 0
+	child := func(c reflect.Value) {
+		deepest = max(deepest, d.depth(c, n+1))
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return 0
+		}
+		k := pointerKey(v)
+		if d.seen[k] {
+			return 0
+		}
+		// A depth measured at level n0 <= n is limited by d.max-n0,
+		// so it holds at n, limited further by d.max-n. A depth
+		// measured deeper holds only if the limit didn't cut it short.
+		if a, ok := d.done[k]; ok && (a.n <= n || a.depth < d.max-a.n) {
+			return min(a.depth, d.max-n)
+		}
+		d.seen[k] = true
+		depth := d.depth(v.Elem(), n)
+		delete(d.seen, k)
+		d.done[k] = depthAt{n, depth}
+		return depth
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return d.depth(v.Elem(), n)
+	case reflect.Struct:
+		for _, fp := range structPlan(v.Type()) {
+			if fp.exported {
+				child(v.Field(fp.index))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			child(v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			child(iter.Value())
+		}
+	default:
+		return 0
+	}
+	return deepest + 1
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "testing"
+
+func TestSummary(t *testing.T) {
+	cyc := &node{I: 1}
+	cyc.Next = cyc
+	f := New(WithOmitPackage(true))
+	for _, test := range []struct {
+		in   any
+		want string
+	}{
+		{nil, "nil"},
+		{3, "int (max depth 0)"},
+		{"abc", "string len=3 (max depth 0)"},
+		{[]*Player{{Name: "Al"}, nil}, "[]*Player len=2 (Player has 2 fields, max depth 2)"},
+		{map[string][]int{"a": {1}}, "map[string][]int len=1 (max depth 2)"},
+		{&node{I: 1, Next: &node{I: 2}}, "*node (node has 2 fields, max depth 2)"},
+		{cyc, "*node (node has 2 fields, max depth 1)"},
+		{team{Players: []Player{{}}}, "team (team has 2 fields, max depth 3)"},
+	} {
+		if got := f.Summary(test.in); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.in, got, test.want)
+		}
+	}
+	// Shared values are measured once.
+	var d *dag
+	for range 60 {
+		d = &dag{d, d}
+	}
+	if got, want := f.Summary(d), "*dag (dag has 2 fields, max depth 60)"; got != want {
+		t.Errorf("DAG: got %q, want %q", got, want)
+	}
+
+	f.MaxDepth = 2
+	if got, want := f.Summary([][][]int{{{1}}}), "[][][]int len=1 (max depth 2)"; got != want {
+		t.Errorf("MaxDepth 2: got %q, want %q", got, want)
+	}
+}
+
+// A dag is a node in a directed acyclic graph.
+type dag struct{ L, R *dag }
@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"reflect"
+	"sync"
+)
+
+var syncMapType = reflect.TypeFor[sync.Map]()
+
+// isSync reports whether values of type t are printed by printSync.
+func (f *Formatter) isSync(t reflect.Type) bool {
+	if f.RawSync || t.Kind() != reflect.Struct {
+		return false
+	}
+	if t == syncMapType {
+		return true
+	}
+	_, ok := reflect.PointerTo(t).MethodByName("Load")
+	return ok && t.PkgPath() == "sync/atomic"
+}
+
+// printSync prints a sync.Map as a map, and a value of one of the
+// sync/atomic types as the value its Load method returns.
+// It reports whether it printed v.
+// The contents of unexported fields can't be loaded, so they are
+// printed like other structs.
+func (s *state) printSync(v reflect.Value) bool {
+	if !s.isSync(v.Type()) || !v.CanInterface() {
+		return false
+	}
+	// Load and Range have pointer receivers.
+	p := addressOf(v)
+	if v.Type() == syncMapType {
+		var es []mapEntry
+		p.Interface().(*sync.Map).Range(func(k, v any) bool {
+			es = append(es, mapEntry{reflect.ValueOf(k), reflect.ValueOf(v)})
+			return true
+		})
+		err := sortEntries(es)
+		if err == nil {
+			_, err = s.orderEntries(es)
+		}
+		if err != nil {
+			s.printPanic(err)
+			return true
+		}
+		s.prType(syncMapType)
+		s.prc(punctClass, "{")
+		s.printEntries(es, s.maxElements(v.Type()), s.TailElements)
+		return true
+	}
+	s.printSameDepth(p.MethodByName("Load").Call(nil)[0])
+	return true
+}
+
+// addressOf returns a pointer to v, or to a copy of v if it is not
+// addressable, so that methods with pointer receivers can be called.
+func addressOf(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v.Addr()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p
+}
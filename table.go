@@ -0,0 +1,155 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// appendTable appends v to dst as a table, if v is a slice or array of
+// structs or maps, or a pointer to one. It reports whether it did so.
+// Each element is a row. The columns of a struct are its exported fields;
+// the columns of a map are the union of the keys of all the maps.
+func (f *Formatter) appendTable(dst []byte, v reflect.Value) ([]byte, bool) {
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return dst, false
+	}
+	et := v.Type().Elem()
+	if et.Kind() == reflect.Pointer {
+		et = et.Elem()
+	}
+	var header []string
+	var cells func(reflect.Value) []string
+	switch et.Kind() {
+	case reflect.Struct:
+		header, cells = f.structColumns(et)
+	case reflect.Map:
+		header, cells = f.mapColumns(v)
+	default:
+		return dst, false
+	}
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	if f.TabAlign {
+		tw = tabwriter.NewWriter(&buf, 0, f.tabWidth(), 1, '\t', 0)
+	}
+	writeRow := func(cells []string) {
+		tw.Write([]byte(strings.Join(cells, "\t") + "\n"))
+	}
+	// A row with a single cell, padded so as not to disturb the alignment
+	// of the rows around it.
+	writeOnly := func(cell string) {
+		cells := make([]string, max(len(header), 1))
+		cells[0] = cell
+		writeRow(cells)
+	}
+	writeRow(header)
+	limit := f.maxElements(v.Type())
+	for i := range v.Len() {
+		if limit > 0 && i >= limit {
+			writeOnly(f.markers().Truncated)
+			break
+		}
+		e := v.Index(i)
+		if e.Kind() == reflect.Pointer {
+			if e.IsNil() {
+				writeOnly("nil")
+				continue
+			}
+			e = e.Elem()
+		}
+		writeRow(cells(e))
+	}
+	tw.Flush()
+	return append(dst, buf.Bytes()...), true
+}
+
+// structColumns returns the column headers for a table of structs of type t,
+// and a function that returns the cells of a row.
+func (f *Formatter) structColumns(t reflect.Type) ([]string, func(reflect.Value) []string) {
+	var fps []fieldPlan
+	var header []string
+	ignore := f.ignoreFields[t]
+	redact := f.redactFields[t]
+	for _, fp := range f.fieldPlans(t) {
+		name, ok := f.fieldName(fp)
+		if !fp.exported || !ok || f.ignoredField(ignore, fp.field.Name) ||
+			!f.pathSelected(Path{fp.field.Name}) {
+			continue
+		}
+		if slices.Contains(redact, fp.field.Name) {
+			fp.tag.redact = true
+		}
+		fps = append(fps, fp)
+		header = append(header, name)
+	}
+	return header, func(v reflect.Value) []string {
+		var cells []string
+		for _, fp := range fps {
+			fv := v.Field(fp.index)
+			if keep, err := f.keepField(fp.field, fv); err != nil {
+				cells = append(cells, "<"+err.Error()+">")
+			} else if keep {
+				cells = append(cells, f.sprintField(fv, fp.tag))
+			} else {
+				cells = append(cells, "")
+			}
+		}
+		return cells
+	}
+}
+
+// mapColumns returns the column headers for a table of the maps in v,
+// a slice or array, and a function that returns the cells of a row.
+// A map without a key has an empty cell in that column.
+func (f *Formatter) mapColumns(v reflect.Value) ([]string, func(reflect.Value) []string) {
+	var keys []reflect.Value
+	seen := map[string]bool{}
+	for i := range v.Len() {
+		m := v.Index(i)
+		if m.Kind() == reflect.Pointer {
+			if m.IsNil() {
+				continue
+			}
+			m = m.Elem()
+		}
+		// The keys are sorted below, so the order of the entries doesn't matter.
+		es, _ := mapEntries(m)
+		for _, e := range es {
+			if k := f.sprintCompact(e.key); !seen[k] {
+				seen[k] = true
+				keys = append(keys, e.key)
+			}
+		}
+	}
+	// If MapKeyOrder panics, the columns are in no particular order.
+	_ = catch(func() { slices.SortFunc(keys, f.compareKeys) })
+	var header []string
+	for _, k := range keys {
+		if k.Kind() == reflect.String {
+			header = append(header, k.String())
+		} else {
+			header = append(header, f.sprintCompact(k))
+		}
+	}
+	return header, func(m reflect.Value) []string {
+		var cells []string
+		for _, k := range keys {
+			if e := m.MapIndex(k); e.IsValid() {
+				cells = append(cells, f.sprintCompact(e))
+			} else {
+				cells = append(cells, "")
+			}
+		}
+		return cells
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import "testing"
+
+func TestTable(t *testing.T) {
+	f := New(WithTable(true), WithMaxElements(3))
+	f.IgnoreFields(Player{}, "Score")
+	for _, test := range []struct {
+		in   any
+		want string
+	}{
+		{
+			[]Player{{Name: "Al"}, {Name: "Barbara", Score: 2}},
+			"Name\n" +
+				"\"Al\"\n" +
+				"\"Barbara\"\n",
+		},
+		{
+			[]*node{{I: 1}, nil, {I: 22}, {I: 3}},
+			"I    Next\n" +
+				"1    &nil\n" +
+				"nil  \n" +
+				"22   &nil\n" +
+				"...  \n",
+		},
+		{
+			[]map[string]int{{"a": 1, "bb": 2}, {"c": 3}},
+			"a  bb  c\n" +
+				"1  2   \n" +
+				"       3\n",
+		},
+		{
+			[]tagged{{Flags: 10, Secret: "x"}},
+			"Zero  Secret      Flags  Neg  Data  Text\n" +
+				"0     <redacted>  0xa    0x0  []{}  \"\"\n",
+		},
+		// Not tables.
+		{[]int{1}, "[]{\n    1,\n}\n"},
+	} {
+		if got := f.Sprint(test.in); got != test.want {
+			t.Errorf("%v:\ngot\n%q\nwant\n%q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestTableTabs(t *testing.T) {
+	f := New(WithTable(true), WithTabAlign(true), WithTabWidth(4))
+	got := f.Sprint([]map[string]int{{"a": 1, "bbbbbb": 2}, {"a": 300}})
+	want := "a\tbbbbbb\n" +
+		"1\t2\n" +
+		"300\t\n"
+	if got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
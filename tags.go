@@ -0,0 +1,136 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// tagOptions are the options of a "format" struct tag.
+// The tag value is a comma-separated list of:
+//
+//	"-"        omit the field
+//	omitzero   omit the field if it is zero, even if ShowZero is set
+//	redact     print <redacted> instead of the value; see [Formatter.Redact]
+//	hex        print an integer in hex, or a byte slice with BytesHex
+//	oct        print an integer in octal
+//	bin        print an integer in binary
+//	string     print a byte slice with BytesString
+//	json       print a byte slice or string that holds JSON as that JSON, re-indented
+type tagOptions struct {
+	omit     bool
+	omitZero bool
+	redact   bool
+	hex      bool
+	base     int // for integers; 0 means the Formatter's choice
+	string   bool
+	json     bool
+}
+
+func parseTag(sf reflect.StructField) tagOptions {
+	var opts tagOptions
+	tag, ok := sf.Tag.Lookup("format")
+	if !ok {
+		return opts
+	}
+	for _, o := range strings.Split(tag, ",") {
+		switch strings.TrimSpace(o) {
+		case "-":
+			opts.omit = true
+		case "omitzero":
+			opts.omitZero = true
+		case "redact":
+			opts.redact = true
+		case "hex":
+			opts.hex = true
+			opts.base = 16
+		case "oct":
+			opts.base = 8
+		case "bin":
+			opts.base = 2
+		case "string":
+			opts.string = true
+		case "json":
+			opts.json = true
+		}
+	}
+	return opts
+}
+
+// printField prints the value of a struct field according to its tag options.
+func (s *state) printField(v reflect.Value, opts tagOptions) {
+	isBytes := (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8
+	switch {
+	case opts.redact:
+		s.deeper(func() { s.prc(markerClass, s.markers().Redacted) })
+	case opts.base != 0 && v.CanInt():
+		s.deeper(func() {
+			s.prcAppend(numberClass, func(b []byte) []byte { return appendInt(b, v.Int(), opts.base) })
+		})
+	case opts.base != 0 && v.CanUint():
+		s.deeper(func() {
+			s.prcAppend(numberClass, func(b []byte) []byte { return appendUint(b, v.Uint(), opts.base) })
+		})
+	case opts.hex && isBytes:
+		s.deeper(func() { s.printBytes(v, BytesHex) })
+	case opts.string && isBytes:
+		s.deeper(func() { s.printBytes(v, BytesString) })
+	case opts.json && !s.GoSyntax && (v.Kind() == reflect.String || (isBytes && v.Kind() == reflect.Slice)):
+		s.deeper(func() {
+			var b []byte
+			if v.Kind() == reflect.String {
+				b = []byte(v.String())
+			} else {
+				b = v.Bytes()
+			}
+			if !s.printJSON(b) {
+				s.printSameDepth(v)
+			}
+		})
+	default:
+		s.print(v)
+	}
+}
+
+// special reports whether the options change how a field's value is printed.
+func (opts tagOptions) special() bool {
+	return opts.redact || opts.hex || opts.base != 0 || opts.string || opts.json
+}
+
+// formatInt formats i in base 2, 8, 10 or 16,
+// with a prefix like those of Go literals.
+func formatInt(i int64, base int) string {
+	return string(appendInt(nil, i, base))
+}
+
+// formatUint is like formatInt, for unsigned integers.
+func formatUint(u uint64, base int) string {
+	return string(appendUint(nil, u, base))
+}
+
+// appendInt appends i to dst as formatInt formats it.
+func appendInt(dst []byte, i int64, base int) []byte {
+	if i < 0 {
+		// Negate as a uint64, so that math.MinInt64 works.
+		return appendUint(append(dst, '-'), -uint64(i), base)
+	}
+	return appendUint(dst, uint64(i), base)
+}
+
+// appendUint appends u to dst as formatUint formats it.
+func appendUint(dst []byte, u uint64, base int) []byte {
+	switch base {
+	case 2:
+		dst = append(dst, "0b"...)
+	case 8:
+		dst = append(dst, "0o"...)
+	case 16:
+		dst = append(dst, "0x"...)
+	default:
+		base = 10
+	}
+	return strconv.AppendUint(dst, u, base)
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// A tree walks a value as a tree of composite values,
+// observing a Formatter's rules for what to display.
+// It underlies the HTML and Mermaid renderers.
+type tree struct {
+	*Formatter
+	maxDepth int
+	path     Path
+	depth    int
+	allPaths bool // track the path even if IgnorePaths and OnlyPaths don't need it
+}
+
+// expands reports whether v has children in the tree: whether it is a
+// non-empty struct, slice, array or map, or a pointer to one, that is not
+// printed in some other way.
+func (t *tree) expands(v reflect.Value) bool {
+	if !v.IsValid() || t.ignoreTypes[v.Type()] || t.redactTypes[v.Type()] ||
+		t.transforms[v.Type()] != nil {
+		return false
+	}
+	if _, ok, _ := t.customString(v); ok {
+		return false
+	}
+	if t.printedSpecially(v.Type()) && v.CanInterface() {
+		return false
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+		if t.ignoreTypes[v.Type()] || t.redactTypes[v.Type()] {
+			return false
+		}
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Array, reflect.Slice:
+		if t.BytesMode != BytesList && v.Type().Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+		return v.Len() > 0
+	case reflect.Map:
+		return v.Len() > 0
+	default:
+		return false
+	}
+}
+
+// printedSpecially reports whether values of type t are printed
+// by special code for their type, rather than according to their kind.
+func (f *Formatter) printedSpecially(t reflect.Type) bool {
+	return f.isSync(t) || isContainer(t) || t == reflectValueType || isSQLNull(t) ||
+		(t == rawBytesType && f.BytesMode == BytesList)
+}
+
+// children calls fn on each child of v, a value for which expands is true
+// after following pointers, with a label for the child: a field name, an
+// index or a formatted map key. It passes a field's tag options as well.
+// It reports whether children were omitted because of MaxElements.
+func (t *tree) children(v reflect.Value, fn func(label string, v reflect.Value, tag tagOptions)) bool {
+	switch v.Kind() {
+	case reflect.Struct:
+		t.structChildren(v, fn)
+		return false
+	case reflect.Array, reflect.Slice:
+		index := func(i int) string { return "[" + strconv.Itoa(i) + "]" }
+		return t.elemChildren(v.Len(), t.maxElements(v.Type()), index, func(i int) {
+			fn(index(i), v.Index(i), tagOptions{})
+		})
+	case reflect.Map:
+		// If sorting panics, the entries are in no particular order.
+		es, _ := mapEntries(v)
+		es, _ = t.orderEntries(es)
+		return t.elemChildren(len(es), t.maxElements(v.Type()), func(i int) string { return keyStep(t.Formatter, es[i].key) },
+			func(i int) { fn(t.sprintCompact(es[i].key), es[i].val, tagOptions{}) })
+	default:
+		return false
+	}
+}
+
+func (t *tree) structChildren(v reflect.Value, fn func(string, reflect.Value, tagOptions)) {
+	t.fieldChildren(v, nil, nil, fn)
+}
+
+// fieldChildren calls fn on the fields of v, a struct, other than those
+// named in shadowed. With Flatten, it calls fn on the fields of embedded
+// structs instead of on the structs themselves, unless they were reached
+// through one of the pointers in outer.
+func (t *tree) fieldChildren(v reflect.Value, shadowed []string, outer []ptrKey, fn func(string, reflect.Value, tagOptions)) {
+	typ := v.Type()
+	ignore := t.ignoreFields[typ]
+	redact := t.redactFields[typ]
+	for _, fp := range t.fieldPlans(typ) {
+		name := fp.field.Name
+		val := v.Field(fp.index)
+		if t.ignoredField(ignore, name) || slices.Contains(shadowed, name) {
+			continue
+		}
+		if elide, _ := t.elideZero(typ, fp, val); elide {
+			continue
+		}
+		// Embedded structs are flattened even if their types are unexported.
+		if t.Flatten && fp.field.Anonymous && !fp.tag.special() && t.expands(val) {
+			inner, ptrs, cycle := val, outer, false
+			if val.Kind() == reflect.Pointer {
+				p := pointerKey(val)
+				inner = val.Elem()
+				ptrs = append(slices.Clip(outer), p)
+				cycle = slices.Contains(outer, p)
+			}
+			if inner.Kind() == reflect.Struct && !cycle {
+				if t.enter(name) {
+					t.fieldChildren(inner, slices.Concat(shadowed, fieldNames(typ)), ptrs, fn)
+					t.leave()
+				}
+				continue
+			}
+		}
+		if !fp.exported {
+			continue
+		}
+		label, ok := t.fieldName(fp)
+		if !ok {
+			continue
+		}
+		// A field is kept if a FilterFields function panics.
+		if keep, _ := t.keepField(fp.field, val); !keep {
+			continue
+		}
+		if !t.enter(name) {
+			continue
+		}
+		tag := fp.tag
+		if slices.Contains(redact, name) {
+			tag.redact = true
+		}
+		fn(label, val, tag)
+		t.leave()
+	}
+}
+
+// elemChildren calls elem on each of n elements of a slice, array or map,
+// up to limit if it is positive. step returns the path step for element i.
+// It reports whether elements were omitted because of the limit.
+func (t *tree) elemChildren(n, limit int, step func(int) string, elem func(int)) bool {
+	printed := 0
+	for i := range n {
+		if !t.enter(step(i)) {
+			continue
+		}
+		if limit > 0 && printed >= limit {
+			t.leave()
+			return true
+		}
+		elem(i)
+		t.leave()
+		printed++
+	}
+	return false
+}
+
+// summaryBytes is the maximum length of a summary, in bytes.
+const summaryBytes = 60
+
+// summary returns the start of v on a single line.
+func (t *tree) summary(v reflect.Value) string {
+	c := *t.Formatter
+	c.MaxBytes = summaryBytes
+	c.MaxLines = 0
+	return c.sprintCompact(v)
+}
+
+// enter adds step to the path, and reports whether the value there is selected.
+func (t *tree) enter(step string) bool {
+	if !t.allPaths && !t.tracksPaths() {
+		return true
+	}
+	t.path = append(t.path, step)
+	if !t.pathSelected(t.path) {
+		t.leave()
+		return false
+	}
+	return true
+}
+
+func (t *tree) leave() {
+	if t.allPaths || t.tracksPaths() {
+		t.path = t.path[:len(t.path)-1]
+	}
+}
+
+// sprintField formats the value of a struct field on one line,
+// according to its tag options.
+func (f *Formatter) sprintField(v reflect.Value, tag tagOptions) string {
+	c := *f
+	c.Compact = true
+	c.MaxWidth = 0
+	c.ignorePaths = nil
+	c.onlyPaths = nil
+	s := c.newState()
+	s.printField(v, tag)
+	str := string(s.buf)
+	s.free()
+	return str
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A TypeNameMode determines how a Formatter qualifies the names of types
+// defined in packages. OmitPackage overrides it.
+type TypeNameMode int
+
+const (
+	TypeNameQualified TypeNameMode = iota // by package name, like http.Request
+	TypeNameFull                          // by import path, like net/http.Request
+	TypeNameLocal                         // not at all for types in LocalPackage, and otherwise by package name
+)
+
+// typeName returns the name of t to print, according to TypeNameFunc,
+// OmitPackage and TypeNames.
+func (f *Formatter) typeName(t reflect.Type) string {
+	if f.TypeNameFunc != nil {
+		if n := f.TypeNameFunc(t); n != "" {
+			return n
+		}
+	}
+	switch {
+	case f.OmitPackage:
+		// Remove every package qualifier, not just the first, so that
+		// composite types like *p.T and map[p.K]q.V keep their structure.
+		return replaceQualifiers(t.String(), func(string) string { return "" })
+	case f.TypeNames == TypeNameFull:
+		return typeString(t, func(pkgPath string) string { return pkgPath + "." })
+	case f.TypeNames == TypeNameLocal:
+		return typeString(t, func(pkgPath string) string {
+			if pkgPath == f.LocalPackage {
+				return ""
+			}
+			return packageName(pkgPath) + "."
+		})
+	default:
+		name := t.String()
+		if !strings.Contains(name, "[") {
+			return name
+		}
+		// reflect qualifies the type arguments of generic types by
+		// import path; use package names, like everywhere else.
+		return replaceQualifiers(name, func(q string) string {
+			return packageName(strings.TrimSuffix(q, ".")) + "."
+		})
+	}
+}
+
+// packageName returns the likely name of the package with the given
+// import path: its last element, ignoring a major version suffix.
+// It returns a package name unchanged.
+func packageName(pkgPath string) string {
+	elems := strings.Split(pkgPath, "/")
+	name := elems[len(elems)-1]
+	if isMajorVersion(name) && len(elems) > 1 {
+		name = elems[len(elems)-2]
+	}
+	// gopkg.in/yaml.v3
+	if i := strings.LastIndexByte(name, '.'); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	return name
+}
+
+// isMajorVersion reports whether s is a major version suffix, like v2.
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+// prType prints the name of t. If the name doesn't fit on the line and
+// t is an instance of a generic type, prType puts each type argument on
+// its own line, breaking the arguments' names in the same way:
+//
+//	Pair[
+//	    map[string]Value[int],
+//	    *Node,
+//	]{
+func (s *state) prType(t reflect.Type) {
+	s.prTypeName(s.typeName(t), "")
+}
+
+// prTypeName prints name followed by suffix, breaking name as prType does.
+func (s *state) prTypeName(name, suffix string) {
+	col := s.col
+	if col == 0 && !s.compact {
+		col = s.advance(0, s.indentation(s.depth))
+	}
+	n := s.width(name + suffix)
+	for _, t := range s.glued {
+		n += s.width(t.str)
+	}
+	head, args, tail, ok := splitTypeArgs(name)
+	if !ok || s.compact || s.maxWidth <= 0 || col+n < s.maxWidth {
+		s.prc(typeClass, name+suffix)
+		return
+	}
+	s.prc(typeClass, head+"[")
+	s.write("\n")
+	s.depth++
+	for _, a := range args {
+		s.prTypeName(a, ",")
+		s.write("\n")
+	}
+	s.depth--
+	s.prc(typeClass, "]"+tail+suffix)
+}
+
+// splitTypeArgs splits the name of a type at its first list of type
+// arguments. For example, it splits "[]p.Pair[map[string]int,T]" into
+// "[]p.Pair", the arguments "map[string]int" and "T", and "".
+// It reports whether name has type arguments.
+func splitTypeArgs(name string) (head string, args []string, tail string, ok bool) {
+	open := -1
+	for i := 1; i < len(name); i++ {
+		// A type argument list follows an identifier other than map.
+		if name[i] == '[' && isIdentByte(name[i-1]) && !isMapKeyword(name[:i]) {
+			open = i
+			break
+		}
+	}
+	if open < 0 {
+		return "", nil, "", false
+	}
+	depth := 0
+	start := open + 1
+	inQuote := false
+	for i := open; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case inQuote:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inQuote = false
+			}
+		case c == '"':
+			inQuote = true
+		case c == '[' || c == '(' || c == '{':
+			depth++
+		case c == ']' || c == ')' || c == '}':
+			depth--
+			if depth == 0 {
+				args = append(args, name[start:i])
+				return name[:open], args, name[i+1:], true
+			}
+		case c == ',' && depth == 1:
+			args = append(args, name[start:i])
+			start = i + 1
+		}
+	}
+	return "", nil, "", false
+}
+
+// isMapKeyword reports whether s ends with the keyword map.
+func isMapKeyword(s string) bool {
+	rest, ok := strings.CutSuffix(s, "map")
+	return ok && (rest == "" || !isIdentByte(rest[len(rest)-1]))
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= utf8.RuneSelf
+}
+
+// typeString returns the name of t, like reflect.Type.String, but with
+// each type defined in a package qualified by qual(its import path).
+func typeString(t reflect.Type, qual func(pkgPath string) string) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name() // predeclared, like int or error
+		}
+		// The type arguments of a generic type are qualified by their import paths.
+		name := replaceQualifiers(t.Name(), func(q string) string {
+			return qual(strings.TrimSuffix(q, "."))
+		})
+		return qual(t.PkgPath()) + name
+	}
+	elem := func() string { return typeString(t.Elem(), qual) }
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + elem()
+	case reflect.Slice:
+		return "[]" + elem()
+	case reflect.Array:
+		return "[" + strconv.Itoa(t.Len()) + "]" + elem()
+	case reflect.Map:
+		return "map[" + typeString(t.Key(), qual) + "]" + elem()
+	case reflect.Chan:
+		switch t.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan " + elem()
+		case reflect.SendDir:
+			return "chan<- " + elem()
+		}
+		if e := t.Elem(); e.Kind() == reflect.Chan && e.Name() == "" && e.ChanDir() == reflect.RecvDir {
+			return "chan (" + elem() + ")"
+		}
+		return "chan " + elem()
+	case reflect.Func:
+		var in, out []string
+		for i := range t.NumIn() {
+			if i == t.NumIn()-1 && t.IsVariadic() {
+				in = append(in, "..."+typeString(t.In(i).Elem(), qual))
+			} else {
+				in = append(in, typeString(t.In(i), qual))
+			}
+		}
+		for i := range t.NumOut() {
+			out = append(out, typeString(t.Out(i), qual))
+		}
+		s := "func(" + strings.Join(in, ", ") + ")"
+		switch len(out) {
+		case 0:
+			return s
+		case 1:
+			return s + " " + out[0]
+		default:
+			return s + " (" + strings.Join(out, ", ") + ")"
+		}
+	case reflect.Struct:
+		if t.NumField() == 0 {
+			return "struct {}"
+		}
+		var fields []string
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			s := typeString(sf.Type, qual)
+			if !sf.Anonymous {
+				s = sf.Name + " " + s
+			}
+			if sf.Tag != "" {
+				s += " " + strconv.Quote(string(sf.Tag))
+			}
+			fields = append(fields, s)
+		}
+		return "struct { " + strings.Join(fields, "; ") + " }"
+	default:
+		// Interfaces with methods keep reflect's qualification.
+		return t.String()
+	}
+}
+
+// packageQualifier matches a package name or path followed by a dot,
+// as it appears in reflect.Type.String.
+var packageQualifier = regexp.MustCompile(`\pL[\w./-]*\.`)
+
+// replaceQualifiers replaces each package qualifier in the type name s
+// with repl(qualifier). It leaves quoted struct tags alone.
+func replaceQualifiers(s string, repl func(string) string) string {
+	var b strings.Builder
+	for s != "" {
+		i := strings.IndexByte(s, '"')
+		if i < 0 {
+			i = len(s)
+		}
+		b.WriteString(packageQualifier.ReplaceAllStringFunc(s[:i], repl))
+		s = s[i:]
+		if s == "" {
+			break
+		}
+		// Copy the quoted tag, up to and including its closing quote.
+		j := 1
+		for j < len(s) && s[j] != '"' {
+			if s[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		j = min(j+1, len(s))
+		b.WriteString(s[:j])
+		s = s[j:]
+	}
+	return b.String()
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type twin[K, V any] struct {
+	K K
+	V V
+}
+
+func TestTypeNames(t *testing.T) {
+	types := []reflect.Type{
+		reflect.TypeFor[*Player](),
+		reflect.TypeFor[map[string][]url.URL](),
+		reflect.TypeFor[twin[int, *url.URL]](),
+		reflect.TypeFor[func(Player, ...error) (int, chan (<-chan url.Values))](),
+		reflect.TypeFor[struct {
+			P Player `json:"p"`
+			url.URL
+		}](),
+		reflect.TypeFor[twin[struct {
+			A int `doc:"see net/url.URL"`
+		}, url.URL]](),
+	}
+	for _, test := range []struct {
+		mode TypeNameMode
+		want []string
+	}{
+		{
+			TypeNameQualified,
+			[]string{
+				"*format.Player",
+				"map[string][]url.URL",
+				"format.twin[int,*url.URL]",
+				"func(format.Player, ...error) (int, chan (<-chan url.Values))",
+				`struct { P format.Player "json:\"p\""; url.URL }`,
+				`format.twin[struct { A int "doc:\"see net/url.URL\"" },url.URL]`,
+			},
+		},
+		{
+			TypeNameFull,
+			[]string{
+				"*github.com/jba/format.Player",
+				"map[string][]net/url.URL",
+				"github.com/jba/format.twin[int,*net/url.URL]",
+				"func(github.com/jba/format.Player, ...error) (int, chan (<-chan net/url.Values))",
+				`struct { P github.com/jba/format.Player "json:\"p\""; net/url.URL }`,
+				`github.com/jba/format.twin[struct { A int "doc:\"see net/url.URL\"" },net/url.URL]`,
+			},
+		},
+		{
+			-1, // OmitPackage
+			[]string{
+				"*Player",
+				"map[string][]URL",
+				"twin[int,*URL]",
+				"func(Player, ...error) (int, chan (<-chan Values))",
+				`struct { P Player "json:\"p\""; URL }`,
+				`twin[struct { A int "doc:\"see net/url.URL\"" },URL]`,
+			},
+		},
+		{
+			TypeNameLocal,
+			[]string{
+				"*Player",
+				"map[string][]url.URL",
+				"twin[int,*url.URL]",
+				"func(Player, ...error) (int, chan (<-chan url.Values))",
+				`struct { P Player "json:\"p\""; url.URL }`,
+				`twin[struct { A int "doc:\"see net/url.URL\"" },url.URL]`,
+			},
+		},
+	} {
+		f := &Formatter{TypeNames: test.mode, LocalPackage: "github.com/jba/format"}
+		if test.mode < 0 {
+			f = &Formatter{OmitPackage: true}
+		}
+		for i, typ := range types {
+			if got := f.typeName(typ); got != test.want[i] {
+				t.Errorf("mode %d: got  %s\nwant %s", test.mode, got, test.want[i])
+			}
+		}
+	}
+}
+
+func TestTypeNameFunc(t *testing.T) {
+	f := &Formatter{
+		Compact:     true,
+		OmitPackage: true,
+		TypeNameFunc: func(t reflect.Type) string {
+			if t.Kind() == reflect.Struct && strings.HasPrefix(t.Name(), "twin[") {
+				return "Twin"
+			}
+			return ""
+		},
+	}
+	in := []any{twin[string, []*url.URL]{K: "a"}, Player{Name: "Al"}}
+	if got, want := f.Sprint(in), `[]{Twin{K: "a"}, Player{Name: "Al"}}`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+type (
+	value[T any] struct{ V T }
+	node2        struct{}
+)
+
+func TestPrTypeName(t *testing.T) {
+	in := &twin[map[string]value[int], *twin[node2, []byte]]{}
+	f := &Formatter{OmitPackage: true, MaxWidth: 30}
+	want := `&twin[
+    map[string]value[int],
+    *twin[node2,[]uint8],
+]{
+}
+`
+	if got := f.Sprint(in); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	f.MaxWidth = 24
+	want = `&twin[
+    map[string]value[
+        int,
+    ],
+    *twin[
+        node2,
+        []uint8,
+    ],
+]{
+}
+`
+	if got := f.Sprint(in); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSplitTypeArgs(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want []string // head, args..., tail
+	}{
+		{"int", nil},
+		{"map[string]int", nil},
+		{"[]heatmap[int]", []string{"[]heatmap", "int", ""}},
+		{"p.Pair[map[string]p.V[int],struct { A int \"a]\" }]", []string{"p.Pair", "map[string]p.V[int]", `struct { A int "a]" }`, ""}},
+	} {
+		head, args, tail, ok := splitTypeArgs(test.in)
+		var got []string
+		if ok {
+			got = append(append([]string{head}, args...), tail)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	for _, test := range []struct{ in, want string }{
+		{"format", "format"},
+		{"net/url", "url"},
+		{"example.com/mod/v2", "mod"},
+		{"gopkg.in/yaml.v3", "yaml"},
+	} {
+		if got := packageName(test.in); got != test.want {
+			t.Errorf("packageName(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Val calls [Formatter.Val] with the default Formatter.
+func Val(x any) Value { return New().Val(x) }
+
+// Val returns a Value that formats x with f when printed by the fmt package,
+// so formatted values can be passed to functions like log.Printf and t.Errorf.
+func (f *Formatter) Val(x any) Value { return Value{f, x} }
+
+// A Value is a value paired with a Formatter.
+// It implements [fmt.Formatter]:
+//
+//   - %v and %s print the value compactly, on a single line
+//   - %+v prints the value according to the Formatter's settings,
+//     which may span several lines
+//   - %#v prints the value as Go syntax
+//
+// Other verbs format the value as the fmt package would.
+type Value struct {
+	f *Formatter
+	x any
+}
+
+// Format implements [fmt.Formatter].
+func (v Value) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		b := v.f.appendValue(nil, reflect.ValueOf(v.x), nil)
+		s.Write(bytes.TrimSuffix(b, []byte("\n")))
+	case verb == 'v' && s.Flag('#'):
+		c := *v.f
+		c.GoSyntax = true
+		io.WriteString(s, c.sprintLine(reflect.ValueOf(v.x)))
+	case verb == 'v' || verb == 's':
+		io.WriteString(s, v.f.sprintLine(reflect.ValueOf(v.x)))
+	default:
+		fmt.Fprintf(s, fmt.FormatString(s, verb), v.x)
+	}
+}
+
+// sprintLine formats v on a single line.
+// Unlike sprintCompact, it honors all of f's other settings.
+func (f *Formatter) sprintLine(v reflect.Value) string {
+	c := *f
+	c.Compact = true
+	c.MaxWidth = 0
+	c.Prefix = ""
+	c.HeaderFunc = nil
+	return sprintBuf(func(b []byte) []byte { return c.appendValue(b, v, nil) })
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"reflect"
+	"slices"
+)
+
+// An Action tells [Formatter.Walk] how to proceed after visiting a value.
+type Action int
+
+const (
+	Continue     Action = iota // visit the value's children
+	SkipChildren               // don't visit the value's children
+	Stop                       // stop walking
+)
+
+// Walk calls [Formatter.Walk] with the default Formatter.
+func Walk(x any, fn func(Path, reflect.Value) Action) { New().Walk(x, fn) }
+
+// Walk calls fn on x and on the values within it, in the order that
+// Sprint prints them, passing the path to each from x.
+// It visits the values that f would print, observing its rules for
+// omitting fields and types and its MaxDepth and MaxElements limits.
+// Values that f prints in some special way, like those with a method or
+// function that formats them and struct fields with tag options, are
+// visited but their children are not. Walk does not follow pointer cycles.
+// Interfaces are replaced by the values they hold.
+func (f *Formatter) Walk(x any, fn func(Path, reflect.Value) Action) {
+	w := &walker{
+		tree: tree{Formatter: f, maxDepth: f.maxDepth(), allPaths: true},
+		fn:   fn,
+		seen: map[ptrKey]bool{},
+	}
+	w.walk(reflect.ValueOf(x), true)
+}
+
+type walker struct {
+	tree
+	fn      func(Path, reflect.Value) Action
+	seen    map[ptrKey]bool // pointers on the current path, to detect cycles
+	stopped bool
+}
+
+// walk visits v and, if expand is true, its children.
+func (w *walker) walk(v reflect.Value, expand bool) {
+	if w.stopped || w.depth > w.maxDepth {
+		return
+	}
+	if v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch w.fn(slices.Clone(w.path), v) {
+	case Stop:
+		w.stopped = true
+		return
+	case SkipChildren:
+		return
+	}
+	if !expand || !w.expands(v) {
+		return
+	}
+	elem := v
+	if v.Kind() == reflect.Pointer {
+		p := pointerKey(v)
+		if w.seen[p] {
+			return
+		}
+		w.seen[p] = true
+		defer delete(w.seen, p)
+		elem = v.Elem()
+	}
+	w.depth++
+	w.children(elem, func(_ string, v reflect.Value, tag tagOptions) {
+		w.walk(v, !tag.special())
+	})
+	w.depth--
+}
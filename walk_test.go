@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Jonathan Amsterdam
+// Use of this source code is governed by the license in the LICENSE file.
+
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	n := &node{I: 1, Next: &node{I: 2}}
+	n.Next.Next = n
+	in := map[string]any{
+		"n": n,
+		"s": []int{1, 2},
+		"t": tagged{Secret: "x"},
+	}
+	for _, test := range []struct {
+		f     *Formatter
+		until string // path to stop at
+		skip  string // path whose children are skipped
+		want  string
+	}{
+		{
+			f: New(),
+			want: `: map[string]interface {}
+["n"]: *format.node
+["n"].I: int
+["n"].Next: *format.node
+["n"].Next.I: int
+["n"].Next.Next: *format.node
+["s"]: []int
+["s"][0]: int
+["s"][1]: int
+["t"]: format.tagged
+["t"].Secret: string
+`,
+		},
+		{
+			f:    New(WithMaxElements(1)),
+			skip: `["n"]`,
+			want: ": map[string]interface {}\n[\"n\"]: *format.node\n",
+		},
+		{
+			f:     New(WithMaxDepth(1)).IgnoreFields(node{}, "I"),
+			until: `["s"]`,
+			want:  ": map[string]interface {}\n[\"n\"]: *format.node\n[\"s\"]: []int\n",
+		},
+	} {
+		var b strings.Builder
+		test.f.Walk(in, func(p Path, v reflect.Value) Action {
+			fmt.Fprintf(&b, "%s: %s\n", p, v.Type())
+			switch p.String() {
+			case "":
+			case test.until:
+				return Stop
+			case test.skip:
+				return SkipChildren
+			}
+			return Continue
+		})
+		if got := b.String(); got != test.want {
+			t.Errorf("got\n%s\nwant\n%s", got, test.want)
+		}
+	}
+}